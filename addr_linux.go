@@ -0,0 +1,443 @@
+package tipc
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// ServiceNameAddr returns the address of the service {typ, instance}, for use
+// with WriteTo and the Dial functions. domain limits the lookup to a node or
+// cluster, or is zero to look up the service anywhere within scope, which is
+// one of unix.TIPC_NODE_SCOPE, unix.TIPC_CLUSTER_SCOPE or
+// unix.TIPC_ZONE_SCOPE.
+func ServiceNameAddr(typ, instance, domain uint32, scope int) *Addr {
+	return &Addr{Sockaddr: &unix.SockaddrTIPC{
+		Scope: scope,
+		Addr:  &unix.TIPCServiceName{Type: typ, Instance: instance, Domain: domain},
+	}}
+}
+
+// ServiceRangeAddr returns the address of the service range {typ, lower,
+// upper}, for multicast sends with WriteTo. Messages reach only the
+// sockets bound within scope, which is one of unix.TIPC_NODE_SCOPE,
+// unix.TIPC_CLUSTER_SCOPE or unix.TIPC_ZONE_SCOPE; a node scope destination
+// never leaves the local node.
+func ServiceRangeAddr(typ, lower, upper uint32, scope int) *Addr {
+	return &Addr{Sockaddr: &unix.SockaddrTIPC{
+		Scope: scope,
+		Addr:  &unix.TIPCServiceRange{Type: typ, Lower: lower, Upper: upper},
+	}}
+}
+
+// ParseAddr parses an address in the form produced by Addr.String. The
+// recognized forms are
+//
+//	port=<ref>,node=<node>
+//	type=<type>,instance=<instance>,domain=<node>
+//	type=<type>,lower=<lower>,upper=<upper>
+//
+// where <node> is either a legacy node address in hex, or a node identity
+// prefixed with "id:", as in "node=id:node1". An optional scope=<scope>
+// element, with scope one of "node", "cluster" or "zone", sets the address
+// scope, which otherwise defaults to cluster scope.
+func ParseAddr(s string) (*Addr, error) {
+	fields := make(map[string]string)
+
+	for _, f := range strings.Split(s, ",") {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return nil, &AddrParseError{Addr: s, Err: fmt.Sprintf("malformed element %q", f)}
+		}
+
+		if _, ok := fields[kv[0]]; ok {
+			return nil, &AddrParseError{Addr: s, Err: fmt.Sprintf("duplicate element %q", kv[0])}
+		}
+
+		fields[kv[0]] = kv[1]
+	}
+
+	sa := &unix.SockaddrTIPC{Scope: unix.TIPC_CLUSTER_SCOPE}
+
+	if v, ok := fields["scope"]; ok {
+		scope, err := parseScope(v)
+		if err != nil {
+			return nil, &AddrParseError{Addr: s, Err: err.Error()}
+		}
+
+		sa.Scope = scope
+		delete(fields, "scope")
+	}
+
+	var (
+		vals [3]uint32
+		keys []string
+		err  error
+	)
+
+	switch {
+	case has(fields, "port", "node"):
+		keys = []string{"port", "node"}
+	case has(fields, "type", "instance", "domain"):
+		keys = []string{"type", "instance", "domain"}
+	case has(fields, "type", "lower", "upper"):
+		keys = []string{"type", "lower", "upper"}
+	default:
+		return nil, &AddrParseError{Addr: s, Err: "unknown address form"}
+	}
+
+	for i, k := range keys {
+		if k == "node" || k == "domain" {
+			vals[i], err = parseNode(fields[k])
+		} else {
+			vals[i], err = parseUint32(fields[k])
+		}
+
+		if err != nil {
+			return nil, &AddrParseError{Addr: s, Err: fmt.Sprintf("bad %s: %v", k, err)}
+		}
+	}
+
+	switch keys[1] {
+	case "node":
+		sa.Addr = &unix.TIPCSocketAddr{Ref: vals[0], Node: vals[1]}
+	case "instance":
+		sa.Addr = &unix.TIPCServiceName{Type: vals[0], Instance: vals[1], Domain: vals[2]}
+	case "lower":
+		sa.Addr = &unix.TIPCServiceRange{Type: vals[0], Lower: vals[1], Upper: vals[2]}
+	}
+
+	return &Addr{Sockaddr: sa}, nil
+}
+
+// has reports whether fields consists of exactly keys.
+func has(fields map[string]string, keys ...string) bool {
+	if len(fields) != len(keys) {
+		return false
+	}
+
+	for _, k := range keys {
+		if _, ok := fields[k]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseScope(s string) (int, error) {
+	switch s {
+	case "node":
+		return unix.TIPC_NODE_SCOPE, nil
+	case "cluster":
+		return unix.TIPC_CLUSTER_SCOPE, nil
+	case "zone":
+		return unix.TIPC_ZONE_SCOPE, nil
+	}
+
+	return 0, fmt.Errorf("unknown scope %q", s)
+}
+
+func parseUint32(s string) (uint32, error) {
+	v, err := strconv.ParseUint(s, 10, 32)
+	return uint32(v), err
+}
+
+func parseNode(s string) (uint32, error) {
+	if strings.HasPrefix(s, "id:") {
+		id, err := ParseNodeID(s[3:])
+		if err != nil {
+			return 0, err
+		}
+
+		return id.Addr(), nil
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	return uint32(v), err
+}
+
+// AddrParseError is returned by ParseAddr for a malformed address.
+type AddrParseError struct {
+	Addr string
+	Err  string
+}
+
+func (e *AddrParseError) Error() string {
+	return fmt.Sprintf("tipc: invalid address %q: %s", e.Addr, e.Err)
+}
+
+// SetNodeID sets the node of a socket address, or the lookup domain of a
+// service name, to the node with the given identity. Service ranges have no
+// node, and an error is returned for them.
+func (a *Addr) SetNodeID(id NodeID) error {
+	ta, ok := a.Sockaddr.(*unix.SockaddrTIPC)
+	if !ok {
+		return fmt.Errorf("tipc: not a tipc address: %T", a.Sockaddr)
+	}
+
+	switch sa := ta.Addr.(type) {
+	case *unix.TIPCSocketAddr:
+		sa.Node = id.Addr()
+	case *unix.TIPCServiceName:
+		sa.Domain = id.Addr()
+	default:
+		return fmt.Errorf("tipc: cannot set node of %T", ta.Addr)
+	}
+
+	return nil
+}
+
+// NodeID is a 128-bit TIPC node identity, as used by kernels since 4.17 in
+// place of configured 32-bit node addresses.
+type NodeID [unix.TIPC_NODEID_LEN]byte
+
+// ParseNodeID parses a node identity in the form used by the tipc tool: a
+// string of up to 16 letters, digits or any of ".:_-@", which is used as is,
+// or a longer string of up to 32 hex digits.
+func ParseNodeID(s string) (NodeID, error) {
+	var id NodeID
+
+	if s == "" {
+		return id, fmt.Errorf("tipc: empty node identity")
+	}
+
+	if len(s) <= len(id) && isNodeIDString(s) {
+		copy(id[:], s)
+		return id, nil
+	}
+
+	if len(s) > 2*len(id) || !isHex(s) {
+		return id, fmt.Errorf("tipc: invalid node identity %q", s)
+	}
+
+	if len(s)%2 != 0 {
+		s += "0"
+	}
+
+	if _, err := hex.Decode(id[:], []byte(s)); err != nil {
+		return id, fmt.Errorf("tipc: invalid node identity %q: %v", s, err)
+	}
+
+	return id, nil
+}
+
+func isNodeIDChar(c byte) bool {
+	switch {
+	case c >= '0' && c <= '9', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		return true
+	}
+
+	return strings.IndexByte(".:_-@", c) >= 0
+}
+
+func isNodeIDString(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isNodeIDChar(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// String formats the identity the way the kernel does: as a string if it
+// consists only of identity characters padded with zero bytes, and as hex
+// with trailing zeros removed otherwise.
+func (id NodeID) String() string {
+	i := 0
+	for ; i < len(id) && id[i] != 0; i++ {
+		if !isNodeIDChar(id[i]) {
+			break
+		}
+	}
+
+	j := i
+	for ; j < len(id) && id[j] == 0; j++ {
+	}
+
+	if j == len(id) {
+		return string(id[:i])
+	}
+
+	s := strings.TrimRight(hex.EncodeToString(id[:]), "0")
+	if s == "" {
+		s = "0"
+	}
+
+	return s
+}
+
+// Addr returns the 32-bit node address the kernel derives from the identity.
+// A node chooses a different address in the unlikely event of a collision
+// within its cluster.
+func (id NodeID) Addr() uint32 {
+	w0 := binary.BigEndian.Uint32(id[0:])
+	w1 := binary.BigEndian.Uint32(id[4:])
+	w2 := binary.BigEndian.Uint32(id[8:])
+	w3 := binary.BigEndian.Uint32(id[12:])
+
+	if h := w0 ^ w1 ^ w2 ^ w3; h != 0 {
+		return h
+	}
+
+	return w0 | w1 | w2 | w3
+}
+
+// ParseNodeAddr parses a legacy node address in the <zone>.<cluster>.<node>
+// form used by the tipc tools, such as "1.1.2". The zone ranges from 0 to 255
+// and the cluster and node from 0 to 4095.
+func ParseNodeAddr(s string) (uint32, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("tipc: invalid node address %q", s)
+	}
+
+	var v [3]uint64
+
+	for i, bits := range []int{8, 12, 12} {
+		n, err := strconv.ParseUint(parts[i], 10, bits)
+		if err != nil {
+			return 0, fmt.Errorf("tipc: invalid node address %q", s)
+		}
+
+		v[i] = n
+	}
+
+	return uint32(v[0]<<24 | v[1]<<12 | v[2]), nil
+}
+
+// FormatNodeAddr formats a legacy node address in <zone>.<cluster>.<node>
+// form.
+func FormatNodeAddr(addr uint32) string {
+	return fmt.Sprintf("%d.%d.%d", addr>>24, addr>>12&0xfff, addr&0xfff)
+}
+
+// NodeIDFromAddr returns the identity the kernel gives a node configured with
+// the legacy address addr: the address in hex, as a string.
+func NodeIDFromAddr(addr uint32) NodeID {
+	var id NodeID
+	copy(id[:], strconv.FormatUint(uint64(addr), 16))
+	return id
+}
+
+func (a *Addr) tipcAddr() unix.TIPCAddr {
+	if a == nil {
+		return nil
+	}
+
+	ta, ok := a.Sockaddr.(*unix.SockaddrTIPC)
+	if !ok {
+		return nil
+	}
+
+	return ta.Addr
+}
+
+// Node returns the node of a socket address.
+func (a *Addr) Node() (uint32, bool) {
+	if sa, ok := a.tipcAddr().(*unix.TIPCSocketAddr); ok {
+		return sa.Node, true
+	}
+
+	return 0, false
+}
+
+// PortRef returns the port reference of a socket address.
+func (a *Addr) PortRef() (uint32, bool) {
+	if sa, ok := a.tipcAddr().(*unix.TIPCSocketAddr); ok {
+		return sa.Ref, true
+	}
+
+	return 0, false
+}
+
+// ServiceType returns the service type of a service name or service range.
+func (a *Addr) ServiceType() (uint32, bool) {
+	switch sa := a.tipcAddr().(type) {
+	case *unix.TIPCServiceName:
+		return sa.Type, true
+	case *unix.TIPCServiceRange:
+		return sa.Type, true
+	}
+
+	return 0, false
+}
+
+// Instance returns the instance of a service name.
+func (a *Addr) Instance() (uint32, bool) {
+	if sa, ok := a.tipcAddr().(*unix.TIPCServiceName); ok {
+		return sa.Instance, true
+	}
+
+	return 0, false
+}
+
+// Domain returns the lookup domain of a service name.
+func (a *Addr) Domain() (uint32, bool) {
+	if sa, ok := a.tipcAddr().(*unix.TIPCServiceName); ok {
+		return sa.Domain, true
+	}
+
+	return 0, false
+}
+
+// Bounds returns the lower and upper instances of a service range.
+func (a *Addr) Bounds() (lower, upper uint32, ok bool) {
+	if sa, ok := a.tipcAddr().(*unix.TIPCServiceRange); ok {
+		return sa.Lower, sa.Upper, true
+	}
+
+	return 0, 0, false
+}
+
+// Equal reports whether a and b are the same TIPC address: both socket
+// addresses with the same port reference and node, service names with the
+// same type, instance, domain and scope, or service ranges with the same
+// type, bounds and scope. The scope of socket addresses, which does not
+// affect delivery, and the Net field are ignored. Two nil addresses are
+// equal, while addresses that are not TIPC addresses equal nothing.
+func (a *Addr) Equal(b *Addr) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	ta, ok := a.Sockaddr.(*unix.SockaddrTIPC)
+	if !ok {
+		return false
+	}
+
+	tb, ok := b.Sockaddr.(*unix.SockaddrTIPC)
+	if !ok {
+		return false
+	}
+
+	switch x := ta.Addr.(type) {
+	case *unix.TIPCSocketAddr:
+		y, ok := tb.Addr.(*unix.TIPCSocketAddr)
+		return ok && *x == *y
+	case *unix.TIPCServiceName:
+		y, ok := tb.Addr.(*unix.TIPCServiceName)
+		return ok && *x == *y && ta.Scope == tb.Scope
+	case *unix.TIPCServiceRange:
+		y, ok := tb.Addr.(*unix.TIPCServiceRange)
+		return ok && *x == *y && ta.Scope == tb.Scope
+	}
+
+	return false
+}
@@ -0,0 +1,193 @@
+package tipc
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const defaultBufferedWriterSize = 4096
+
+var errNotStream = errors.New("buffered writes require a stream connection")
+
+// BufferedWriter coalesces small writes to a stream connection into fewer,
+// larger writes. Data is written to the connection when the buffer fills,
+// when the flush interval has passed since the first write into an empty
+// buffer, on Flush and on Close. It is safe for concurrent use.
+//
+// An error from a write made by the flush timer is returned by the next
+// Write, Flush or Close, and all writes fail after it.
+type BufferedWriter struct {
+	conn     *Conn
+	interval time.Duration
+
+	mu     sync.Mutex
+	buf    []byte
+	timer  *time.Timer
+	err    error
+	closed bool
+}
+
+// BufferedWriter returns a BufferedWriter buffering up to size bytes of
+// writes to the connection, and flushing them at most flushInterval after
+// they were written. A size of zero or less selects a 4 KiB buffer, and a
+// flushInterval of zero or less disables the flush timer.
+//
+// Coalescing would merge the messages of a SOCK_SEQPACKET connection, so
+// only stream connections are supported. Writes made directly on the
+// connection bypass the buffer, and may overtake data buffered before them.
+func (tc *Conn) BufferedWriter(size int, flushInterval time.Duration) (*BufferedWriter, error) {
+	if tc.typ != unix.SOCK_STREAM {
+		return nil, tc.opError("write", errNotStream)
+	}
+
+	if size <= 0 {
+		size = defaultBufferedWriterSize
+	}
+
+	return &BufferedWriter{
+		conn:     tc,
+		interval: flushInterval,
+		buf:      make([]byte, 0, size),
+	}, nil
+}
+
+// Write buffers p, writing the buffer to the connection as it fills. A write
+// larger than the buffer is passed on directly, after the data buffered
+// before it.
+func (bw *BufferedWriter) Write(p []byte) (int, error) {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	if err := bw.check(); err != nil {
+		return 0, err
+	}
+
+	if len(bw.buf)+len(p) > cap(bw.buf) {
+		if err := bw.flushLocked(); err != nil {
+			return 0, err
+		}
+
+		if len(p) >= cap(bw.buf) {
+			n, err := bw.conn.Write(p)
+			if err != nil {
+				bw.err = err
+			}
+
+			return n, err
+		}
+	}
+
+	bw.buf = append(bw.buf, p...)
+
+	if len(bw.buf) == cap(bw.buf) {
+		if err := bw.flushLocked(); err != nil {
+			// p is in the buffer, and is written by a later successful
+			// flush.
+			return len(p), err
+		}
+
+		return len(p), nil
+	}
+
+	if bw.timer == nil && bw.interval > 0 && len(bw.buf) > 0 {
+		bw.timer = time.AfterFunc(bw.interval, bw.timedFlush)
+	}
+
+	return len(p), nil
+}
+
+// Flush writes any buffered data to the connection.
+func (bw *BufferedWriter) Flush() error {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	if err := bw.check(); err != nil {
+		return err
+	}
+
+	return bw.flushLocked()
+}
+
+// Buffered returns the number of bytes waiting to be written.
+func (bw *BufferedWriter) Buffered() int {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	return len(bw.buf)
+}
+
+// Close flushes any buffered data and then closes the connection. The
+// connection is closed even if the flush fails.
+func (bw *BufferedWriter) Close() error {
+	bw.mu.Lock()
+
+	var err error
+	if !bw.closed {
+		err = bw.err
+		if err == nil {
+			err = bw.flushLocked()
+		}
+
+		bw.closed = true
+	}
+
+	bw.stopTimer()
+	bw.mu.Unlock()
+
+	if cerr := bw.conn.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+func (bw *BufferedWriter) check() error {
+	if bw.closed {
+		return bw.conn.opError("write", net.ErrClosed)
+	}
+
+	return bw.err
+}
+
+func (bw *BufferedWriter) timedFlush() {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	bw.timer = nil
+
+	if bw.check() == nil {
+		bw.flushLocked()
+	}
+}
+
+// flushLocked writes the buffer to the connection. On failure the data not
+// written is kept, and the error is recorded. bw.mu must be held.
+func (bw *BufferedWriter) flushLocked() error {
+	bw.stopTimer()
+
+	if len(bw.buf) == 0 {
+		return nil
+	}
+
+	n, err := bw.conn.Write(bw.buf)
+
+	rest := copy(bw.buf, bw.buf[n:])
+	bw.buf = bw.buf[:rest]
+
+	if err != nil {
+		bw.err = err
+	}
+
+	return err
+}
+
+func (bw *BufferedWriter) stopTimer() {
+	if bw.timer != nil {
+		bw.timer.Stop()
+		bw.timer = nil
+	}
+}
@@ -0,0 +1,120 @@
+package tipc
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Caps describes the TIPC features supported by the running kernel.
+type Caps struct {
+	// SupportsGroups reports support for communication groups, used by
+	// JoinGroup and ListenGroup (Linux 4.14).
+	SupportsGroups bool
+
+	// SupportsNodeIdentity reports support for 128-bit node identities,
+	// used by PeerNodeID (Linux 4.17).
+	SupportsNodeIdentity bool
+
+	// SupportsReplicast reports whether sockets can select replicast for
+	// multicast with SetMulticastMethod (Linux 4.15). Older kernels may
+	// still use replicast, but choose it themselves.
+	SupportsReplicast bool
+}
+
+// capProbe tests for a feature on a SOCK_RDM socket, returning the error of
+// the system call that uses it.
+type capProbe struct {
+	op    string
+	probe func(fd int) error
+}
+
+var (
+	// Joining a group is the only way to set TIPC_GROUP_JOIN, but kernels
+	// with groups also answer getsockopt for it on any socket.
+	probeGroups = capProbe{"getsockopt", func(fd int) error {
+		_, err := unix.GetsockoptInt(fd, unix.SOL_TIPC, unix.TIPC_GROUP_JOIN)
+		return err
+	}}
+
+	// Node 0 is never known, so kernels with node identities fail the
+	// lookup with EADDRNOTAVAIL rather than rejecting the ioctl.
+	probeNodeIdentity = capProbe{"ioctl", func(fd int) error {
+		var req unix.TIPCSIOCNodeIDReq
+
+		_, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), siocGetNodeID, uintptr(unsafe.Pointer(&req)))
+		switch errno {
+		case 0, unix.EADDRNOTAVAIL:
+			return nil
+		}
+
+		return errno
+	}}
+
+	// The option takes no value, and is only accepted with a NULL optval;
+	// kernels without it reject it as an unknown option with EINVAL. The
+	// probe socket is closed right after, so selecting replicast on it has
+	// no lasting effect.
+	probeReplicast = capProbe{"setsockopt", func(fd int) error {
+		_, _, errno := unix.Syscall6(unix.SYS_SETSOCKOPT, uintptr(fd), unix.SOL_TIPC, unix.TIPC_MCAST_REPLICAST, 0, 0, 0)
+		if errno != 0 {
+			return errno
+		}
+
+		return nil
+	}}
+)
+
+// Capabilities detects the TIPC features supported by the running kernel, by
+// trying each of them on a new SOCK_RDM socket. It fails if TIPC itself is not
+// available, so that applications can tell a kernel lacking a feature from one
+// lacking TIPC.
+func Capabilities() (Caps, error) {
+	fd, err := socket(unix.SOCK_RDM)
+	if err != nil {
+		return Caps{}, err
+	}
+
+	defer unix.Close(fd)
+
+	return probeCaps(fd, probeGroups, probeNodeIdentity, probeReplicast)
+}
+
+func probeCaps(fd int, groups, nodeID, replicast capProbe) (Caps, error) {
+	var (
+		caps Caps
+		err  error
+	)
+
+	if caps.SupportsGroups, err = groups.supported(fd); err != nil {
+		return Caps{}, err
+	}
+
+	if caps.SupportsNodeIdentity, err = nodeID.supported(fd); err != nil {
+		return Caps{}, err
+	}
+
+	if caps.SupportsReplicast, err = replicast.supported(fd); err != nil {
+		return Caps{}, err
+	}
+
+	return caps, nil
+}
+
+// supported runs the probe, and reports whether the feature is supported.
+// The kernel rejects an unknown TIPC socket option with EINVAL, an unknown
+// level with ENOPROTOOPT, and an unknown ioctl with ENOTTY or EOPNOTSUPP; any
+// other failure is returned.
+func (p capProbe) supported(fd int) (bool, error) {
+	err := p.probe(fd)
+
+	switch err {
+	case nil:
+		return true, nil
+	case unix.ENOPROTOOPT, unix.EINVAL, unix.ENOTTY, unix.EOPNOTSUPP:
+		return false, nil
+	}
+
+	return false, os.NewSyscallError(p.op, err)
+}
@@ -0,0 +1,87 @@
+package tipc
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Dialer contains options for connecting to a service. The zero value is
+// equivalent to calling DialStream, DialSequentialPacket or DialAddr.
+//
+// TIPC refuses to connect a socket that is bound to a service, so a dialing
+// socket cannot be given a service name of its own. A client that needs to be
+// identified by name can publish it on a separate socket, which the server
+// can look up with topology.PortServices. TIPC also has no equivalent of
+// SO_BINDTODEVICE: the bearer a connection uses is chosen by link priority,
+// which is configured per bearer with netlink.BearerSet.
+type Dialer struct {
+	// If Trace is not nil, its hooks are called as connections are made
+	// and closed.
+	Trace *ConnTrace
+}
+
+// DialStream connects a stream socket to s.
+func (d *Dialer) DialStream(s *unix.SockaddrTIPC) (*Conn, error) {
+	return d.dial(unix.SOCK_STREAM, s)
+}
+
+// DialSequentialPacket connects a SOCK_SEQPACKET socket to s.
+func (d *Dialer) DialSequentialPacket(s *unix.SockaddrTIPC) (*Conn, error) {
+	return d.dial(unix.SOCK_SEQPACKET, s)
+}
+
+// Dial connects to addr on the named network. Known networks are
+// "tipc-stream" and "tipc-seqpacket".
+func (d *Dialer) Dial(network string, addr *Addr) (*Conn, error) {
+	var typ int
+
+	switch network {
+	case "tipc-stream":
+		typ = unix.SOCK_STREAM
+	case "tipc-seqpacket":
+		typ = unix.SOCK_SEQPACKET
+	default:
+		return nil, net.UnknownNetworkError(network)
+	}
+
+	if addr == nil {
+		return nil, errMissingAddress
+	}
+
+	sa, ok := addr.Sockaddr.(*unix.SockaddrTIPC)
+	if !ok {
+		return nil, &net.AddrError{Err: "expected tipc sockaddr", Addr: fmt.Sprintf("%T", addr.Sockaddr)}
+	}
+
+	return d.dial(typ, sa)
+}
+
+func (d *Dialer) dial(typ int, s *unix.SockaddrTIPC) (*Conn, error) {
+	trace := d.Trace
+	if trace == nil {
+		return newConnectConn(typ, s, nil)
+	}
+
+	network := socketNetwork(typ)
+	addr := &Addr{Sockaddr: s, Net: network}
+
+	if trace.ConnectStart != nil {
+		trace.ConnectStart(network, addr)
+	}
+
+	start := time.Now()
+	c, err := newConnectConn(typ, s, nil)
+
+	if trace.ConnectDone != nil {
+		trace.ConnectDone(network, addr, time.Since(start), err)
+	}
+
+	if c != nil {
+		c.trace = trace
+	}
+
+	return c, err
+}
@@ -0,0 +1,119 @@
+package tipc
+
+import (
+	"errors"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// FileForExec returns a duplicate of the listening socket that is inherited
+// across exec, for handing the listener to a child process, for instance
+// during a graceful restart. The child rebuilds the listener with
+// ListenerFromFile. The duplicate shares the socket's bindings and
+// connection queue with the listener, so either process can accept
+// connections until the other closes its descriptor.
+//
+// Unlike the listener's own descriptor, the duplicate does not have
+// close-on-exec set, so it is passed to every child process started while it
+// is open. Close it once the intended child has started. Children started
+// with os/exec do not need it: Cmd.ExtraFiles clears close-on-exec on the
+// files it passes itself, so a file returned by FileForExec is only needed
+// when using syscall.Exec or another mechanism that does not.
+func (l *Listener) FileForExec() (*os.File, error) {
+	nfd, err := l.conn.dup("file")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := unix.FcntlInt(uintptr(nfd), unix.F_SETFD, 0); err != nil {
+		unix.Close(nfd)
+		return nil, l.conn.opError("file", os.NewSyscallError("fcntl", err))
+	}
+
+	return os.NewFile(uintptr(nfd), "tipc-listener"), nil
+}
+
+var errNotTIPCListener = errors.New("not a listening TIPC stream or seqpacket socket")
+
+// ListenerFromFile returns a Listener for the listening socket open as f,
+// such as one inherited from a parent that called FileForExec. It works on a
+// duplicate of the descriptor, so f may be closed afterwards. An error is
+// returned if f is not a listening AF_TIPC stream or seqpacket socket.
+//
+// The service ranges the socket is bound to are not known, so the
+// listener's ServiceRange and Bindings are empty and its Addr is the port
+// address of the socket.
+func ListenerFromFile(f *os.File) (*Listener, error) {
+	sc, err := f.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		nfd  = -1
+		typ  int
+		ferr error
+	)
+
+	cerr := sc.Control(func(fd uintptr) {
+		if typ, ferr = checkListener(int(fd)); ferr != nil {
+			return
+		}
+
+		nfd, ferr = unix.FcntlInt(fd, unix.F_DUPFD_CLOEXEC, 0)
+		if ferr != nil {
+			ferr = os.NewSyscallError("fcntl", ferr)
+		}
+	})
+
+	if cerr == nil {
+		cerr = ferr
+	}
+
+	if cerr != nil {
+		return nil, &net.OpError{Op: "file", Net: opNetwork(typ), Err: cerr}
+	}
+
+	if err := unix.SetNonblock(nfd, true); err != nil {
+		unix.Close(nfd)
+		return nil, &net.OpError{Op: "file", Net: opNetwork(typ), Err: os.NewSyscallError("setnonblock", err)}
+	}
+
+	conn, err := newConn(nfd)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Listener{conn: conn}, nil
+}
+
+// checkListener returns the type of fd, and an error unless it is a listening
+// TIPC stream or seqpacket socket.
+func checkListener(fd int) (int, error) {
+	domain, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_DOMAIN)
+	if err != nil {
+		if err == unix.ENOTSOCK {
+			return 0, errNotTIPCListener
+		}
+
+		return 0, os.NewSyscallError("getsockopt", err)
+	}
+
+	typ, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_TYPE)
+	if err != nil {
+		return 0, os.NewSyscallError("getsockopt", err)
+	}
+
+	listening, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_ACCEPTCONN)
+	if err != nil {
+		return 0, os.NewSyscallError("getsockopt", err)
+	}
+
+	if domain != unix.AF_TIPC || (typ != unix.SOCK_STREAM && typ != unix.SOCK_SEQPACKET) || listening == 0 {
+		return 0, errNotTIPCListener
+	}
+
+	return typ, nil
+}
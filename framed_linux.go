@@ -0,0 +1,160 @@
+package tipc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrFrameTooLarge is returned by FramedConn when a frame exceeds the maximum
+// frame size.
+var ErrFrameTooLarge = errors.New("tipc: frame exceeds maximum size")
+
+const defaultMaxFrameSize = 1 << 20
+
+// FrameOptions configures the length prefix used by a FramedConn.
+type FrameOptions struct {
+	// PrefixSize is the size of the length prefix in bytes: 1, 2, 4 or 8.
+	// It defaults to 4.
+	PrefixSize int
+
+	// ByteOrder is the byte order of the length prefix. It defaults to
+	// binary.BigEndian.
+	ByteOrder binary.ByteOrder
+
+	// MaxFrameSize is the largest frame payload accepted by ReadFrame and
+	// WriteFrame. It defaults to 1 MiB, and is also limited by what the
+	// prefix can represent.
+	MaxFrameSize int
+}
+
+// FramedConn reads and writes length-prefixed frames over a stream, such as
+// a stream Conn, whose reads do not preserve the boundaries of writes. Each
+// frame is its payload preceded by its length.
+//
+// ReadFrame and WriteFrame may each be called from one goroutine at a time;
+// a frame read and a frame write may proceed concurrently.
+type FramedConn struct {
+	rw    io.ReadWriter
+	size  int
+	order binary.ByteOrder
+	max   uint64
+
+	rmu  sync.Mutex
+	rhdr [8]byte
+
+	wmu sync.Mutex
+}
+
+// NewFramedConn returns a FramedConn reading and writing frames on rw. The
+// FramedConn must be the only reader of rw, since a read that consumes part
+// of a frame breaks the framing of the rest of the stream.
+func NewFramedConn(rw io.ReadWriter, opts FrameOptions) (*FramedConn, error) {
+	size := opts.PrefixSize
+	if size == 0 {
+		size = 4
+	}
+
+	switch size {
+	case 1, 2, 4, 8:
+	default:
+		return nil, fmt.Errorf("tipc: invalid frame prefix size %d", size)
+	}
+
+	order := opts.ByteOrder
+	if order == nil {
+		order = binary.BigEndian
+	}
+
+	max := uint64(opts.MaxFrameSize)
+	if opts.MaxFrameSize <= 0 {
+		max = defaultMaxFrameSize
+	}
+
+	if limit := uint64(1)<<(8*uint(size)) - 1; size < 8 && max > limit {
+		max = limit
+	}
+
+	return &FramedConn{rw: rw, size: size, order: order, max: max}, nil
+}
+
+// ReadFrame reads the next frame and returns its payload. It returns io.EOF
+// if the stream ends between frames and io.ErrUnexpectedEOF if it ends within
+// one. A frame longer than the maximum frame size is not read, and
+// ErrFrameTooLarge is returned; the stream can no longer be read as frames
+// after that, and should be closed.
+func (fc *FramedConn) ReadFrame() ([]byte, error) {
+	fc.rmu.Lock()
+	defer fc.rmu.Unlock()
+
+	hdr := fc.rhdr[:fc.size]
+
+	if _, err := io.ReadFull(fc.rw, hdr); err != nil {
+		return nil, err
+	}
+
+	n := fc.decode(hdr)
+	if n > fc.max {
+		return nil, ErrFrameTooLarge
+	}
+
+	p := make([]byte, n)
+
+	if _, err := io.ReadFull(fc.rw, p); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// WriteFrame writes p as a single frame, with the prefix and payload passed
+// to one Write call. It returns ErrFrameTooLarge, without writing anything,
+// if p exceeds the maximum frame size.
+func (fc *FramedConn) WriteFrame(p []byte) error {
+	if uint64(len(p)) > fc.max {
+		return ErrFrameTooLarge
+	}
+
+	buf := make([]byte, fc.size+len(p))
+	fc.encode(buf[:fc.size], uint64(len(p)))
+	copy(buf[fc.size:], p)
+
+	fc.wmu.Lock()
+	defer fc.wmu.Unlock()
+
+	_, err := fc.rw.Write(buf)
+
+	return err
+}
+
+func (fc *FramedConn) decode(b []byte) uint64 {
+	switch fc.size {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(fc.order.Uint16(b))
+	case 4:
+		return uint64(fc.order.Uint32(b))
+	}
+
+	return fc.order.Uint64(b)
+}
+
+func (fc *FramedConn) encode(b []byte, n uint64) {
+	switch fc.size {
+	case 1:
+		b[0] = byte(n)
+	case 2:
+		fc.order.PutUint16(b, uint16(n))
+	case 4:
+		fc.order.PutUint32(b, uint32(n))
+	default:
+		fc.order.PutUint64(b, n)
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/mischief/tipc
 
-go 1.13
+go 1.16
 
 require (
 	golang.org/x/net v0.0.0-20200324143707-d3edc9973b7e
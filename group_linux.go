@@ -0,0 +1,116 @@
+package tipc
+
+import (
+	"errors"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Flags for JoinGroup.
+const (
+	// GroupLoopback delivers messages sent to the group by this socket
+	// back to itself.
+	GroupLoopback = unix.TIPC_GROUP_LOOPBACK
+
+	// GroupMemberEvents delivers membership events for the group.
+	GroupMemberEvents = unix.TIPC_GROUP_MEMBER_EVTS
+)
+
+var errNotGroupMember = errors.New("socket is not a group member")
+
+// JoinGroup makes the socket a member of the communication group typ, bound
+// as instance within scope. flags is a combination of GroupLoopback and
+// GroupMemberEvents. Group membership requires a SOCK_RDM socket, and a
+// socket can be a member of at most one group.
+func (tc *Conn) JoinGroup(typ, instance uint32, scope int, flags uint32) error {
+	req := &unix.TIPCGroupReq{
+		Type:     typ,
+		Instance: instance,
+		Scope:    uint32(scope),
+		Flags:    flags,
+	}
+
+	tc.groupmu.Lock()
+	defer tc.groupmu.Unlock()
+
+	if err := tc.setGroupReq(req); err != nil {
+		return err
+	}
+
+	tc.group = req
+
+	return nil
+}
+
+// LeaveGroup leaves the group joined with JoinGroup.
+func (tc *Conn) LeaveGroup() error {
+	tc.groupmu.Lock()
+	defer tc.groupmu.Unlock()
+
+	if err := tc.setsockoptNoValue(unix.SOL_TIPC, unix.TIPC_GROUP_LEAVE); err != nil {
+		return err
+	}
+
+	tc.group = nil
+
+	return nil
+}
+
+// SetGroupLoopback sets whether messages the socket sends to its group are
+// delivered back to it. The kernel only reads the loopback flag when a socket
+// joins a group, so SetGroupLoopback leaves the group and joins it again with
+// the new flag; other members see the socket leave and rejoin.
+func (tc *Conn) SetGroupLoopback(on bool) error {
+	tc.groupmu.Lock()
+	defer tc.groupmu.Unlock()
+
+	if tc.group == nil {
+		return tc.opError("setsockopt", errNotGroupMember)
+	}
+
+	req := *tc.group
+	if on {
+		req.Flags |= GroupLoopback
+	} else {
+		req.Flags &^= GroupLoopback
+	}
+
+	if req.Flags == tc.group.Flags {
+		return nil
+	}
+
+	if err := tc.setsockoptNoValue(unix.SOL_TIPC, unix.TIPC_GROUP_LEAVE); err != nil {
+		return err
+	}
+
+	tc.group = nil
+
+	if err := tc.setGroupReq(&req); err != nil {
+		return err
+	}
+
+	tc.group = &req
+
+	return nil
+}
+
+func (tc *Conn) setGroupReq(req *unix.TIPCGroupReq) error {
+	var errno unix.Errno
+
+	cerr := tc.sc.Control(func(fd uintptr) {
+		_, _, errno = unix.Syscall6(unix.SYS_SETSOCKOPT, fd, unix.SOL_TIPC, unix.TIPC_GROUP_JOIN,
+			uintptr(unsafe.Pointer(req)), unsafe.Sizeof(*req), 0)
+	})
+
+	if cerr != nil {
+		return tc.opError("setsockopt", cerr)
+	}
+
+	if errno != 0 {
+		return tc.opError("setsockopt", os.NewSyscallError("setsockopt", errno))
+	}
+
+	return nil
+}
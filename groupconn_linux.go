@@ -0,0 +1,260 @@
+package tipc
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// GroupEvent is the kind of a message received by GroupConn.Recv.
+type GroupEvent int
+
+const (
+	// GroupMessage is a data message sent by a member.
+	GroupMessage GroupEvent = iota
+
+	// GroupMemberJoined reports a member that joined the group, or was
+	// already a member when the socket joined.
+	GroupMemberJoined
+
+	// GroupMemberLeft reports a member that left the group or was closed.
+	GroupMemberLeft
+)
+
+func (e GroupEvent) String() string {
+	switch e {
+	case GroupMessage:
+		return "message"
+	case GroupMemberJoined:
+		return "member joined"
+	case GroupMemberLeft:
+		return "member left"
+	}
+
+	return fmt.Sprintf("GroupEvent(%d)", int(e))
+}
+
+// GroupMember identifies a member of a communication group.
+type GroupMember struct {
+	// Addr is the port identity of the member's socket, which can be
+	// passed to GroupConn.SendTo.
+	Addr *Addr
+
+	// Instance is the instance the member joined the group as.
+	Instance uint32
+}
+
+// GroupConn is a SOCK_RDM socket that is a member of a communication group.
+// Messages sent through a GroupConn only reach other members of the group,
+// and are subject to the group's flow control: a send to a member that is
+// not keeping up with its messages waits until the member catches up, or
+// until the write deadline expires.
+type GroupConn struct {
+	conn *Conn
+	typ  uint32
+}
+
+// ListenGroup returns a new SOCK_RDM socket joined to the communication group
+// typ as instance within scope. flags is a combination of GroupLoopback and
+// GroupMemberEvents; with GroupMemberEvents, Recv reports members joining and
+// leaving the group along with the messages they send.
+func ListenGroup(typ, instance uint32, scope int, flags uint32) (*GroupConn, error) {
+	c, err := ReliableDatagram()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.JoinGroup(typ, instance, scope, flags); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return &GroupConn{conn: c, typ: typ}, nil
+}
+
+// Send sends p to a single member that joined the group as instance. If
+// several members share the instance, TIPC picks one of them, favouring
+// members with room in their receive window.
+func (gc *GroupConn) Send(p []byte, instance uint32) (int, error) {
+	return gc.write(p, &unix.SockaddrTIPC{
+		Scope: unix.TIPC_CLUSTER_SCOPE,
+		Addr: &unix.TIPCServiceName{
+			Type:     gc.typ,
+			Instance: instance,
+		},
+	})
+}
+
+// SendTo sends p to the member with the port identity addr, such as the
+// address of a member returned by Recv.
+func (gc *GroupConn) SendTo(p []byte, addr *Addr) (int, error) {
+	if addr == nil {
+		return 0, gc.conn.opError("write", errMissingAddress)
+	}
+
+	return gc.write(p, addr.Sockaddr)
+}
+
+// Multicast sends p to every member that joined the group as instance.
+func (gc *GroupConn) Multicast(p []byte, instance uint32) (int, error) {
+	return gc.write(p, &unix.SockaddrTIPC{
+		Scope: unix.TIPC_CLUSTER_SCOPE,
+		Addr: &unix.TIPCServiceRange{
+			Type:  gc.typ,
+			Lower: instance,
+			Upper: instance,
+		},
+	})
+}
+
+// Broadcast sends p to every member of the group. It is only delivered back
+// to the sending socket if the group was joined with GroupLoopback.
+func (gc *GroupConn) Broadcast(p []byte) (int, error) {
+	// A send without a destination is a group broadcast.
+	return gc.write(p, nil)
+}
+
+func (gc *GroupConn) write(p []byte, to unix.Sockaddr) (int, error) {
+	n, err := gc.conn.sendmsg(p, nil, to, 0)
+	if err != nil {
+		if _, ok := err.(unix.Errno); ok {
+			err = os.NewSyscallError("sendmsg", err)
+		}
+
+		return 0, gc.conn.opError("write", err)
+	}
+
+	return n, nil
+}
+
+// Recv reads the next message or membership event, copying the payload of a
+// message into p. It returns the number of bytes copied, the member that
+// sent the message or whose membership changed, and the kind of message.
+// Membership events carry no payload. A message longer than p is truncated.
+//
+// A message sent by the socket that could not be delivered is returned by
+// TIPC and reported as a *RejectedError.
+func (gc *GroupConn) Recv(p []byte) (n int, from GroupMember, ev GroupEvent, err error) {
+	tc := gc.conn
+
+	if err := tc.extendIdle(); err != nil {
+		return 0, GroupMember{}, 0, tc.opError("read", err)
+	}
+
+	var (
+		names [2]unix.RawSockaddrTIPC
+		oob   = make([]byte, rejectOOBSize)
+		msg   unix.Msghdr
+		iov   unix.Iovec
+		rerr  error
+	)
+
+	if len(p) > 0 {
+		iov.Base = &p[0]
+		iov.SetLen(len(p))
+	}
+
+	cerr := tc.sc.Read(func(fd uintptr) bool {
+		rerr = ignoringEINTR(func() error {
+			// unix.Recvmsg only decodes the first address, while a group
+			// member's instance is passed in a second one.
+			msg = unix.Msghdr{
+				Name:    (*byte)(unsafe.Pointer(&names[0])),
+				Namelen: uint32(unsafe.Sizeof(names)),
+				Iov:     &iov,
+				Control: &oob[0],
+			}
+			msg.SetIovlen(1)
+			msg.SetControllen(len(oob))
+
+			r, _, errno := unix.Syscall(unix.SYS_RECVMSG, fd, uintptr(unsafe.Pointer(&msg)), 0)
+			if errno != 0 {
+				return errno
+			}
+
+			n = int(r)
+
+			return nil
+		})
+
+		return rerr != unix.EAGAIN
+	})
+
+	if cerr != nil {
+		return 0, GroupMember{}, 0, tc.opError("read", cerr)
+	}
+
+	if rerr != nil {
+		return 0, GroupMember{}, 0, tc.opError("read", os.NewSyscallError("recvmsg", rerr))
+	}
+
+	tc.countRead(n)
+
+	from = groupMember(names[:], int(msg.Namelen), tc.network())
+
+	if e := parseRejection(oob[:msg.Controllen]); e != nil {
+		return 0, from, 0, e
+	}
+
+	// Membership events are flagged MSG_OOB, and departures also MSG_EOR.
+	switch {
+	case msg.Flags&unix.MSG_OOB == 0:
+		return n, from, GroupMessage, nil
+	case msg.Flags&unix.MSG_EOR != 0:
+		return 0, from, GroupMemberLeft, nil
+	}
+
+	return 0, from, GroupMemberJoined, nil
+}
+
+// groupMember decodes the addresses filled in by recvmsg on a group socket:
+// the port identity of the sender, followed by its group membership.
+func groupMember(names []unix.RawSockaddrTIPC, namelen int, network string) GroupMember {
+	var m GroupMember
+
+	size := int(unsafe.Sizeof(names[0]))
+
+	if namelen >= size && names[0].Addrtype == unix.TIPC_SOCKET_ADDR {
+		id := *(*unix.TIPCSocketAddr)(unsafe.Pointer(&names[0].Addr))
+		m.Addr = &Addr{
+			Sockaddr: &unix.SockaddrTIPC{Addr: &id},
+			Net:      network,
+		}
+	}
+
+	if namelen >= 2*size && names[1].Addrtype == unix.TIPC_SERVICE_ADDR {
+		m.Instance = (*unix.TIPCServiceName)(unsafe.Pointer(&names[1].Addr)).Instance
+	}
+
+	return m
+}
+
+// LocalAddr returns the port identity of the socket.
+func (gc *GroupConn) LocalAddr() net.Addr {
+	return gc.conn.LocalAddr()
+}
+
+// SetDeadline sets the read and write deadlines of the socket.
+func (gc *GroupConn) SetDeadline(t time.Time) error {
+	return gc.conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the deadline for Recv.
+func (gc *GroupConn) SetReadDeadline(t time.Time) error {
+	return gc.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for sends, which may wait for group
+// flow control.
+func (gc *GroupConn) SetWriteDeadline(t time.Time) error {
+	return gc.conn.SetWriteDeadline(t)
+}
+
+// Close leaves the group and closes the socket.
+func (gc *GroupConn) Close() error {
+	return gc.conn.Close()
+}
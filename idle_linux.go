@@ -0,0 +1,70 @@
+package tipc
+
+import (
+	"time"
+)
+
+// SetIdleTimeout makes every read on the connection wait at most d for data,
+// by moving the read deadline to d from the start of each read. A connection
+// that stays silent for longer than d fails its pending read with a timeout
+// error, while one that keeps receiving data stays open indefinitely. A
+// duration of zero or less turns the idle timeout off.
+//
+// A deadline set with SetDeadline or SetReadDeadline still applies: each read
+// uses whichever of the two expires first. When the idle timeout is turned
+// off, the deadline set with those methods is restored.
+func (tc *Conn) SetIdleTimeout(d time.Duration) error {
+	tc.idlemu.Lock()
+	defer tc.idlemu.Unlock()
+
+	if d <= 0 {
+		tc.idle = 0
+		tc.idleAt = time.Time{}
+		return tc.fil.SetReadDeadline(tc.rdeadline)
+	}
+
+	tc.idle = d
+
+	return tc.extendIdleLocked()
+}
+
+// extendIdle moves the read deadline forward by the idle timeout, if one is
+// set. It is called at the start of each read.
+func (tc *Conn) extendIdle() error {
+	tc.idlemu.Lock()
+	defer tc.idlemu.Unlock()
+
+	if tc.idle <= 0 {
+		return nil
+	}
+
+	return tc.extendIdleLocked()
+}
+
+func (tc *Conn) extendIdleLocked() error {
+	tc.idleAt = time.Now().Add(tc.idle)
+
+	return tc.fil.SetReadDeadline(tc.effectiveReadDeadline())
+}
+
+// effectiveReadDeadline returns the earlier of the explicit read deadline and
+// the idle deadline. tc.idlemu must be held.
+func (tc *Conn) effectiveReadDeadline() time.Time {
+	t := tc.rdeadline
+	if !tc.idleAt.IsZero() && (t.IsZero() || tc.idleAt.Before(t)) {
+		t = tc.idleAt
+	}
+
+	return t
+}
+
+// setReadDeadline records t as the explicit read deadline and applies it,
+// limited by the idle deadline.
+func (tc *Conn) setReadDeadline(t time.Time) error {
+	tc.idlemu.Lock()
+	defer tc.idlemu.Unlock()
+
+	tc.rdeadline = t
+
+	return tc.fil.SetReadDeadline(tc.effectiveReadDeadline())
+}
@@ -0,0 +1,115 @@
+package tipc
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// AcceptResult is a connection, or the error that ended the accept loop,
+// delivered by Listener.Incoming.
+type AcceptResult struct {
+	Conn *Conn
+	Err  error
+}
+
+var aLongTimeAgo = time.Unix(1, 0)
+
+const (
+	minAcceptBackoff = 5 * time.Millisecond
+	maxAcceptBackoff = time.Second
+)
+
+// Incoming accepts connections in a new goroutine and delivers them on the
+// returned channel. Temporary errors, such as running out of file
+// descriptors, are retried with exponential backoff. Any other error is
+// delivered as the final result, after which the channel is closed. The
+// channel is also closed when ctx is done; Incoming uses the listener's
+// deadline to interrupt a pending Accept, and clears it on return.
+func (l *Listener) Incoming(ctx context.Context) <-chan AcceptResult {
+	ch := make(chan AcceptResult)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-ctx.Done():
+			l.SetDeadline(aLongTimeAgo)
+		case <-done:
+		}
+	}()
+
+	go func() {
+		defer close(ch)
+		defer func() {
+			close(done)
+			<-stopped
+
+			if ctx.Err() != nil {
+				l.SetDeadline(time.Time{})
+			}
+		}()
+
+		var backoff time.Duration
+
+		for {
+			c, err := l.AcceptTIPC()
+			if ctx.Err() != nil {
+				if c != nil {
+					c.Close()
+				}
+
+				return
+			}
+
+			if err != nil {
+				if isTemporary(err) {
+					if backoff == 0 {
+						backoff = minAcceptBackoff
+					} else if backoff *= 2; backoff > maxAcceptBackoff {
+						backoff = maxAcceptBackoff
+					}
+
+					select {
+					case <-time.After(backoff):
+						continue
+					case <-ctx.Done():
+						return
+					}
+				}
+
+				select {
+				case ch <- AcceptResult{Err: err}:
+				case <-ctx.Done():
+				}
+
+				return
+			}
+
+			backoff = 0
+
+			select {
+			case ch <- AcceptResult{Conn: c}:
+			case <-ctx.Done():
+				c.Close()
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+// isTemporary reports whether err is a temporary accept error worth
+// retrying. Timeouts from a deadline set by the caller are not.
+func isTemporary(err error) bool {
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		return false
+	}
+
+	te, ok := err.(interface{ Temporary() bool })
+
+	return ok && te.Temporary()
+}
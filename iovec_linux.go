@@ -0,0 +1,156 @@
+package tipc
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxIOV is the maximum number of buffers passed to a single writev or
+// readv, as limited by IOV_MAX.
+const maxIOV = 1024
+
+// WriteBuffers writes the contents of bufs to the connection using vectored
+// writes, so that data split across several buffers, such as a header and a
+// payload, is sent without being copied or sent in separate syscalls. On a
+// stream connection a short write is continued with the remaining data. It
+// returns the total number of bytes written.
+func (tc *Conn) WriteBuffers(bufs [][]byte) (int, error) {
+	iovs := make([][]byte, 0, len(bufs))
+	for _, b := range bufs {
+		if len(b) > 0 {
+			iovs = append(iovs, b)
+		}
+	}
+
+	var (
+		total int
+		werr  error
+	)
+
+	cerr := tc.sc.Write(func(fd uintptr) bool {
+		for len(iovs) > 0 {
+			chunk := iovs
+			if len(chunk) > maxIOV {
+				chunk = chunk[:maxIOV]
+			}
+
+			var n int
+
+			werr = ignoringEINTR(func() (err error) {
+				n, err = unix.Writev(int(fd), chunk)
+				return
+			})
+
+			if errors.Is(werr, unix.EAGAIN) {
+				return false
+			}
+
+			if werr != nil {
+				return true
+			}
+
+			total += n
+			iovs = consumeIOVs(iovs, n)
+			tc.countWrite(n)
+		}
+
+		return true
+	})
+
+	if cerr != nil {
+		return total, tc.opError("write", cerr)
+	}
+
+	if werr != nil {
+		return total, tc.opError("write", os.NewSyscallError("writev", werr))
+	}
+
+	return total, nil
+}
+
+// ReadBuffers reads from the connection into bufs using a single vectored
+// read, filling each buffer in turn, so that for instance a fixed-size header
+// and the following body can be received in one syscall. Like Read, it
+// returns as soon as some data is available, so the buffers may be only
+// partly filled. At most maxIOV buffers are used. It returns the total
+// number of bytes read, or io.EOF when the peer has closed the connection.
+func (tc *Conn) ReadBuffers(bufs [][]byte) (int, error) {
+	iovs := make([][]byte, 0, len(bufs))
+	for _, b := range bufs {
+		if len(b) > 0 {
+			iovs = append(iovs, b)
+		}
+	}
+
+	if len(iovs) == 0 {
+		return 0, nil
+	}
+
+	if len(iovs) > maxIOV {
+		iovs = iovs[:maxIOV]
+	}
+
+	if err := tc.extendIdle(); err != nil {
+		return 0, tc.opError("read", err)
+	}
+
+	var (
+		n    int
+		rerr error
+	)
+
+	cerr := tc.sc.Read(func(fd uintptr) bool {
+		rerr = ignoringEINTR(func() (err error) {
+			n, err = unix.Readv(int(fd), iovs)
+			return
+		})
+
+		return !errors.Is(rerr, unix.EAGAIN)
+	})
+
+	if cerr != nil {
+		return 0, tc.opError("read", cerr)
+	}
+
+	if rerr == nil {
+		tc.countRead(n)
+	}
+
+	if kaerr := tc.keepAliveErr(); kaerr != nil && (rerr != nil || n == 0) {
+		return 0, tc.opError("read", kaerr)
+	}
+
+	// As in Read, a closed peer is reported as ECONNRESET when there is no
+	// control buffer to carry the reason.
+	if rerr == nil && n == 0 {
+		return 0, io.EOF
+	}
+
+	if errors.Is(rerr, unix.ECONNRESET) {
+		return 0, tc.resetError()
+	}
+
+	if rerr != nil {
+		return 0, tc.opError("read", os.NewSyscallError("readv", rerr))
+	}
+
+	return n, nil
+}
+
+// consumeIOVs removes the first n bytes from iovs.
+func consumeIOVs(iovs [][]byte, n int) [][]byte {
+	for len(iovs) > 0 {
+		if n < len(iovs[0]) {
+			iovs[0] = iovs[0][n:]
+			break
+		}
+
+		n -= len(iovs[0])
+		iovs = iovs[1:]
+	}
+
+	return iovs
+}
@@ -0,0 +1,140 @@
+package tipc
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const defaultKeepAlivePeriod = 15 * time.Second
+
+var errKeepAliveType = errors.New("keepalive requires a stream socket")
+
+// SetKeepAlive enables or disables application-level keepalive probes on a
+// stream connection. While enabled, a zero-length message is sent on the
+// connection every keepalive period; stream peers never see these messages.
+// If a probe cannot be sent because the connection has failed, the
+// connection is shut down and subsequent reads return the probe's error.
+//
+// TIPC already supervises connections at the link level: when a peer node
+// becomes unreachable, the link tolerance (1.5 seconds by default) expires
+// and every connection to that node is aborted. Keepalive probes detect
+// nothing the kernel does not already know, but they ensure an idle
+// connection touches the socket regularly, so failures are noticed by
+// applications that only write occasionally.
+func (tc *Conn) SetKeepAlive(enable bool) error {
+	if enable {
+		typ, err := tc.getsockoptInt(unix.SOL_SOCKET, unix.SO_TYPE)
+		if err != nil {
+			return err
+		}
+
+		if typ != unix.SOCK_STREAM {
+			return tc.opError("setkeepalive", errKeepAliveType)
+		}
+	}
+
+	tc.kamu.Lock()
+	defer tc.kamu.Unlock()
+
+	tc.kaEnabled = enable
+	tc.restartKeepAlive()
+
+	return nil
+}
+
+// SetKeepAlivePeriod sets the interval between keepalive probes. The default
+// is 15 seconds.
+func (tc *Conn) SetKeepAlivePeriod(d time.Duration) error {
+	if d <= 0 {
+		return tc.opError("setkeepaliveperiod", errors.New("non-positive keepalive period"))
+	}
+
+	tc.kamu.Lock()
+	defer tc.kamu.Unlock()
+
+	tc.kaPeriod = d
+	tc.restartKeepAlive()
+
+	return nil
+}
+
+// restartKeepAlive stops any running keepalive goroutine and starts a new
+// one if keepalive is enabled. tc.kamu must be held.
+func (tc *Conn) restartKeepAlive() {
+	if tc.kaStop != nil {
+		close(tc.kaStop)
+		tc.kaStop = nil
+	}
+
+	if !tc.kaEnabled {
+		return
+	}
+
+	period := tc.kaPeriod
+	if period == 0 {
+		period = defaultKeepAlivePeriod
+	}
+
+	tc.kaStop = make(chan struct{})
+
+	go tc.keepAlive(tc.kaStop, period)
+}
+
+func (tc *Conn) stopKeepAlive() {
+	tc.kamu.Lock()
+	defer tc.kamu.Unlock()
+
+	tc.kaEnabled = false
+	tc.restartKeepAlive()
+}
+
+func (tc *Conn) keepAlive(stop <-chan struct{}, period time.Duration) {
+	t := time.NewTicker(period)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+
+		var serr error
+
+		cerr := tc.sc.Control(func(fd uintptr) {
+			serr = ignoringEINTR(func() error {
+				_, err := unix.SendmsgN(int(fd), nil, nil, nil, unix.MSG_DONTWAIT|unix.MSG_NOSIGNAL)
+				return err
+			})
+		})
+
+		if cerr != nil {
+			return
+		}
+
+		// A congested connection is still alive.
+		if serr == nil || errors.Is(serr, unix.EAGAIN) {
+			continue
+		}
+
+		tc.kamu.Lock()
+		tc.kaErr = os.NewSyscallError("keepalive", serr)
+		tc.kamu.Unlock()
+
+		tc.shutdown()
+
+		return
+	}
+}
+
+// keepAliveErr returns the error that caused a keepalive probe to fail, if
+// any.
+func (tc *Conn) keepAliveErr() error {
+	tc.kamu.Lock()
+	defer tc.kamu.Unlock()
+
+	return tc.kaErr
+}
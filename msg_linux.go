@@ -0,0 +1,345 @@
+package tipc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// nativeEndian is the byte order used in socket control messages.
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+
+	return binary.BigEndian
+}()
+
+// Error codes carried by a RejectedError.
+const (
+	RejectNoName   = unix.TIPC_ERR_NO_NAME
+	RejectNoPort   = unix.TIPC_ERR_NO_PORT
+	RejectNoNode   = unix.TIPC_ERR_NO_NODE
+	RejectOverload = unix.TIPC_ERR_OVERLOAD
+)
+
+// RejectedError is returned when a message sent from a connectionless socket
+// could not be delivered and was returned by TIPC.
+type RejectedError struct {
+	// Code is the reason the message was rejected, e.g. RejectNoName.
+	Code int
+
+	// Returned is the payload of the rejected message, as handed back by
+	// TIPC, so that it can be retried or logged. It is only set by ReadFrom
+	// and ReadMessage, and is empty if the message had no payload.
+	Returned []byte
+}
+
+func (e *RejectedError) Error() string {
+	var reason string
+
+	switch e.Code {
+	case RejectNoName:
+		reason = "no such service"
+	case RejectNoPort:
+		reason = "no such port"
+	case RejectNoNode:
+		reason = "no such node"
+	case RejectOverload:
+		reason = "destination overloaded"
+	default:
+		reason = fmt.Sprintf("error %d", e.Code)
+	}
+
+	return "tipc: message rejected: " + reason
+}
+
+// room for TIPC_ERRINFO and TIPC_DESTNAME.
+var rejectOOBSize = unix.CmsgSpace(8) + unix.CmsgSpace(12)
+
+// rejectOOBPool holds control buffers of rejectOOBSize, so that reads on the
+// connection path do not allocate one per call.
+var rejectOOBPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, rejectOOBSize)
+		return &b
+	},
+}
+
+// maxMessageSize is TIPC_MAX_USER_MSG_SIZE, the largest message payload.
+const maxMessageSize = 66000
+
+// room for TIPC_ERRINFO and TIPC_DESTNAME along with the largest payload
+// TIPC_RETDATA can carry.
+var returnOOBSize = rejectOOBSize + unix.CmsgSpace(maxMessageSize)
+
+// returnOOBPool holds control buffers of returnOOBSize, which are too large
+// to allocate for every message read.
+var returnOOBPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, returnOOBSize)
+		return &b
+	},
+}
+
+func (tc *Conn) recvmsg(p, oob []byte, flags int) (n, oobn, recvflags int, from unix.Sockaddr, err error) {
+	if err := tc.extendIdle(); err != nil {
+		return 0, 0, 0, nil, err
+	}
+
+	cerr := tc.sc.Read(func(fd uintptr) bool {
+		err = ignoringEINTR(func() (err error) {
+			n, oobn, recvflags, from, err = unix.Recvmsg(int(fd), p, oob, flags)
+			return
+		})
+		return !errors.Is(err, unix.EAGAIN)
+	})
+
+	if cerr != nil {
+		return 0, 0, 0, nil, cerr
+	}
+
+	if err == nil && flags&unix.MSG_PEEK == 0 {
+		tc.countRead(n)
+	}
+
+	return
+}
+
+// parseRejection returns a *RejectedError if oob contains TIPC error
+// information, and nil otherwise.
+func parseRejection(oob []byte) *RejectedError {
+	if len(oob) == 0 {
+		return nil
+	}
+
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil
+	}
+
+	var (
+		rerr *RejectedError
+		data []byte
+	)
+
+	for _, m := range msgs {
+		if m.Header.Level != unix.SOL_TIPC {
+			continue
+		}
+
+		switch m.Header.Type {
+		case unix.TIPC_ERRINFO:
+			if len(m.Data) >= 8 && rerr == nil {
+				rerr = &RejectedError{Code: int(nativeEndian.Uint32(m.Data))}
+			}
+		case unix.TIPC_RETDATA:
+			data = m.Data
+		}
+	}
+
+	if rerr != nil && len(data) > 0 {
+		// oob may be reused, so the payload must be copied out.
+		rerr.Returned = append([]byte(nil), data...)
+	}
+
+	return rerr
+}
+
+func (tc *Conn) sendmsg(p, oob []byte, to unix.Sockaddr, flags int) (n int, err error) {
+	cerr := tc.sc.Write(func(fd uintptr) bool {
+		err = ignoringEINTR(func() (err error) {
+			n, err = unix.SendmsgN(int(fd), p, oob, to, flags)
+			return
+		})
+		return !errors.Is(err, unix.EAGAIN)
+	})
+
+	if cerr != nil {
+		return 0, cerr
+	}
+
+	if err == nil {
+		tc.countWrite(n)
+	}
+
+	return
+}
+
+// ReadMsgTIPC reads a message from the socket, copying the payload into p and
+// the associated control messages into oob. It returns the number of bytes
+// copied into p and oob, the flags set on the message, and the source address
+// of the message, if any.
+func (tc *Conn) ReadMsgTIPC(p, oob []byte) (n, oobn, flags int, addr *Addr, err error) {
+	n, oobn, flags, sa, err := tc.recvmsg(p, oob, 0)
+	if err != nil {
+		return 0, 0, 0, nil, tc.opError("read", err)
+	}
+
+	if sa != nil {
+		addr = tc.newAddr(sa)
+	}
+
+	return n, oobn, flags, addr, nil
+}
+
+// WriteMsgTIPC writes a message to addr, or to the connected peer if addr is
+// nil, copying the payload from p and the control messages from oob. It
+// returns the number of payload and control bytes written.
+func (tc *Conn) WriteMsgTIPC(p, oob []byte, addr *Addr) (n, oobn int, err error) {
+	var to unix.Sockaddr
+	if addr != nil {
+		to = addr.Sockaddr
+	}
+
+	n, err = tc.sendmsg(p, oob, to, 0)
+	if err != nil {
+		return 0, 0, tc.opError("write", err)
+	}
+
+	return n, len(oob), nil
+}
+
+// Peek reads data from the connection without removing it from the receive
+// queue, so that a following Read returns the same data. Like Read, it
+// waits for data to arrive and honors the read deadline.
+func (tc *Conn) Peek(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	n, _, _, _, err := tc.recvmsg(p, nil, unix.MSG_PEEK)
+	if err != nil {
+		if errors.Is(err, unix.ECONNRESET) {
+			return 0, tc.resetError()
+		}
+
+		if _, ok := err.(unix.Errno); ok {
+			err = os.NewSyscallError("recvmsg", err)
+		}
+
+		return 0, tc.opError("read", err)
+	}
+
+	if n == 0 {
+		return 0, io.EOF
+	}
+
+	return n, nil
+}
+
+// ErrWouldBlock is returned by ReadNonblock when no data is available. It
+// wraps unix.EAGAIN.
+var ErrWouldBlock error = &wouldBlockError{}
+
+type wouldBlockError struct{}
+
+func (*wouldBlockError) Error() string   { return "tipc: operation would block" }
+func (*wouldBlockError) Unwrap() error   { return unix.EAGAIN }
+func (*wouldBlockError) Temporary() bool { return true }
+func (*wouldBlockError) Timeout() bool   { return false }
+
+// ReadNonblock performs a single read without waiting for data, for callers
+// that poll the socket for readiness themselves, for instance through
+// SyscallConn. If no data is available it returns ErrWouldBlock
+// immediately. Other results are as for Read.
+func (tc *Conn) ReadNonblock(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	var (
+		n, oobn int
+		rerr    error
+	)
+
+	bp := rejectOOBPool.Get().(*[]byte)
+	defer rejectOOBPool.Put(bp)
+
+	oob := *bp
+
+	cerr := tc.sc.Control(func(fd uintptr) {
+		rerr = ignoringEINTR(func() (err error) {
+			n, oobn, _, _, err = unix.Recvmsg(int(fd), p, oob, unix.MSG_DONTWAIT)
+			return
+		})
+	})
+
+	if cerr != nil {
+		return 0, tc.opError("read", cerr)
+	}
+
+	if rerr == nil {
+		tc.countRead(n)
+	}
+
+	switch {
+	case errors.Is(rerr, unix.EAGAIN):
+		return 0, ErrWouldBlock
+	case errors.Is(rerr, unix.ECONNRESET):
+		return 0, tc.resetError()
+	case rerr != nil:
+		return 0, tc.opError("read", os.NewSyscallError("recvmsg", rerr))
+	case n == 0:
+		return 0, tc.closeError(oob[:oobn])
+	}
+
+	tc.recordService(oob[:oobn])
+
+	return n, nil
+}
+
+// ReadMessage reads a single message from a SOCK_SEQPACKET, SOCK_RDM or
+// SOCK_DGRAM socket. If the message is larger than p, the remainder of the
+// message is discarded and truncated is true; it cannot be recovered by a
+// further read.
+//
+// On a SOCK_SEQPACKET connection, the end of the connection is reported as
+// by Read: io.EOF if the peer shut it down or closed its socket, and an error
+// wrapping ErrConnAbort if it was aborted. On SOCK_RDM and SOCK_DGRAM
+// sockets, a message sent by the socket and rejected by its destination is
+// returned as a *RejectedError.
+func (tc *Conn) ReadMessage(p []byte) (n int, truncated bool, err error) {
+	bp := returnOOBPool.Get().(*[]byte)
+	defer returnOOBPool.Put(bp)
+
+	oob := *bp
+
+	connected := tc.typ == unix.SOCK_SEQPACKET || tc.typ == unix.SOCK_STREAM
+
+	n, oobn, flags, _, err := tc.recvmsg(p, oob, 0)
+	if err != nil {
+		if connected && errors.Is(err, unix.ECONNRESET) {
+			return 0, false, tc.resetError()
+		}
+
+		if _, ok := err.(unix.Errno); ok {
+			err = os.NewSyscallError("recvmsg", err)
+		}
+
+		return 0, false, tc.opError("read", err)
+	}
+
+	if connected {
+		if n == 0 && flags&unix.MSG_TRUNC == 0 {
+			if kaerr := tc.keepAliveErr(); kaerr != nil {
+				return 0, false, tc.opError("read", kaerr)
+			}
+
+			return 0, false, tc.closeError(oob[:oobn])
+		}
+
+		tc.recordService(oob[:oobn])
+	} else if rerr := parseRejection(oob[:oobn]); rerr != nil {
+		return 0, false, rerr
+	}
+
+	return n, flags&unix.MSG_TRUNC != 0, nil
+}
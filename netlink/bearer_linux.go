@@ -0,0 +1,234 @@
+package netlink
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// Limits from linux/tipc.h.
+const (
+	maxMediaName  = 16
+	maxIfName     = 16
+	maxBearerName = 32
+	maxPriority   = 31
+
+	minTolerance = 50 * time.Millisecond
+	maxTolerance = 30 * time.Second
+	minWindow    = 16
+	maxWindow    = 8191
+)
+
+// BearerOptions configures a bearer enabled with BearerEnable.
+type BearerOptions struct {
+	// Domain restricts link setup to nodes within the given network
+	// domain. Zero allows links to any node.
+	Domain uint32
+
+	// Priority sets the priority of links on the bearer, from 1 to 31.
+	// Zero leaves the media default.
+	Priority int
+
+	// Local and Remote are required for udp bearers, and give the local
+	// address to bind and the remote address or multicast group to send
+	// neighbour discovery to. Remote may be nil to use the default
+	// multicast group.
+	Local, Remote *net.UDPAddr
+}
+
+// BearerEnable enables the bearer with the given name, such as "eth:eth0"
+// or "udp:bearer1". It requires CAP_NET_ADMIN.
+func BearerEnable(name string, opts BearerOptions) error {
+	media, err := parseBearerName(name)
+	if err != nil {
+		return err
+	}
+
+	if opts.Priority < 0 || opts.Priority > maxPriority {
+		return fmt.Errorf("netlink: bearer priority %d out of range", opts.Priority)
+	}
+
+	if media == "udp" && opts.Local == nil {
+		return errors.New("netlink: udp bearer requires a local address")
+	}
+
+	if media != "udp" && (opts.Local != nil || opts.Remote != nil) {
+		return fmt.Errorf("netlink: %s bearers do not take udp addresses", media)
+	}
+
+	var ab attrBuilder
+	ab.nested(attrBearer, func(nb *attrBuilder) {
+		nb.str(attrBearerName, name)
+
+		if opts.Domain != 0 {
+			nb.u32(attrBearerDomain, opts.Domain)
+		}
+
+		if opts.Priority != 0 {
+			nb.nested(attrBearerProp, func(pb *attrBuilder) {
+				pb.u32(attrPropPriority, uint32(opts.Priority))
+			})
+		}
+
+		if opts.Local != nil {
+			nb.nested(attrBearerUDP, func(ub *attrBuilder) {
+				ub.add(attrUDPLocal, sockaddr(opts.Local))
+
+				if opts.Remote != nil {
+					ub.add(attrUDPRemote, sockaddr(opts.Remote))
+				}
+			})
+		}
+	})
+
+	return bearerRequest(cmdBearerEnable, ab.bytes())
+}
+
+// BearerDisable disables the bearer with the given name. It requires
+// CAP_NET_ADMIN.
+func BearerDisable(name string) error {
+	if _, err := parseBearerName(name); err != nil {
+		return err
+	}
+
+	var ab attrBuilder
+	ab.nested(attrBearer, func(nb *attrBuilder) {
+		nb.str(attrBearerName, name)
+	})
+
+	return bearerRequest(cmdBearerDisable, ab.bytes())
+}
+
+// BearerProps holds link properties of an enabled bearer, changed with
+// BearerSet. Zero fields are left unchanged.
+type BearerProps struct {
+	// Priority is the priority of links on the bearer, from 1 to 31.
+	Priority int
+
+	// Tolerance is how long a link may go without traffic from its peer
+	// before it is reset, from 50ms to 30s, in whole milliseconds.
+	Tolerance time.Duration
+
+	// Window is the link send window in packets, from 16 to 8191.
+	Window int
+}
+
+// BearerSet changes the link properties of the enabled bearer with the given
+// name. It requires CAP_NET_ADMIN.
+//
+// TIPC has no per-socket or per-message choice of bearer. Unicast traffic to
+// a node travels over its active links, which are those on the bearers with
+// the highest priority, so raising the priority of a bearer steers traffic
+// onto it; links of equal priority share the load. Broadcast traffic is sent
+// on every bearer; to keep it off a bearer, disable the bearer, or force
+// replicast delivery with SetMulticastMethod so that multicast follows the
+// unicast links.
+//
+// Bearer priority is also the only quality of service control TIPC offers:
+// sockets accept no IP_TOS or similar option, and UDP bearers have no TOS
+// setting, so traffic classes are separated by running them over bearers of
+// different priority. Within a link, message importance only sets how much
+// of the send backlog a message may use under congestion.
+func BearerSet(name string, props BearerProps) error {
+	if _, err := parseBearerName(name); err != nil {
+		return err
+	}
+
+	switch {
+	case props.Priority < 0 || props.Priority > maxPriority:
+		return fmt.Errorf("netlink: bearer priority %d out of range", props.Priority)
+	case props.Tolerance != 0 && (props.Tolerance < minTolerance || props.Tolerance > maxTolerance):
+		return fmt.Errorf("netlink: link tolerance %v out of range", props.Tolerance)
+	case props.Window != 0 && (props.Window < minWindow || props.Window > maxWindow):
+		return fmt.Errorf("netlink: link window %d out of range", props.Window)
+	case props == BearerProps{}:
+		return errors.New("netlink: no bearer properties to set")
+	}
+
+	var ab attrBuilder
+	ab.nested(attrBearer, func(nb *attrBuilder) {
+		nb.str(attrBearerName, name)
+		nb.nested(attrBearerProp, func(pb *attrBuilder) {
+			if props.Priority != 0 {
+				pb.u32(attrPropPriority, uint32(props.Priority))
+			}
+
+			if props.Tolerance != 0 {
+				pb.u32(attrPropTolerance, uint32(props.Tolerance/time.Millisecond))
+			}
+
+			if props.Window != 0 {
+				pb.u32(attrPropWindow, uint32(props.Window))
+			}
+		})
+	})
+
+	return bearerRequest(cmdBearerSet, ab.bytes())
+}
+
+func bearerRequest(cmd uint8, attrs []byte) error {
+	c, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	defer c.Close()
+
+	_, err = c.request(cmd, unix.NLM_F_ACK, attrs)
+
+	return err
+}
+
+// parseBearerName validates a bearer name of the form "<media>:<interface>"
+// and returns the media name.
+func parseBearerName(name string) (string, error) {
+	i := strings.IndexByte(name, ':')
+	if i <= 0 || i == len(name)-1 {
+		return "", fmt.Errorf("netlink: invalid bearer name %q: want <media>:<interface>", name)
+	}
+
+	media, iface := name[:i], name[i+1:]
+
+	switch {
+	case len(name) >= maxBearerName:
+		return "", fmt.Errorf("netlink: bearer name %q too long", name)
+	case len(media) >= maxMediaName, len(iface) >= maxIfName:
+		return "", fmt.Errorf("netlink: invalid bearer name %q", name)
+	}
+
+	switch media {
+	case "eth", "ib", "udp":
+	default:
+		return "", fmt.Errorf("netlink: unknown bearer media %q", media)
+	}
+
+	return media, nil
+}
+
+// sockaddr encodes addr as a struct sockaddr_in or sockaddr_in6.
+func sockaddr(addr *net.UDPAddr) []byte {
+	if ip4 := addr.IP.To4(); ip4 != nil {
+		b := make([]byte, unix.SizeofSockaddrInet4)
+		nativeEndian.PutUint16(b[0:], unix.AF_INET)
+		b[2], b[3] = byte(addr.Port>>8), byte(addr.Port)
+		copy(b[4:8], ip4)
+		return b
+	}
+
+	b := make([]byte, unix.SizeofSockaddrInet6)
+	nativeEndian.PutUint16(b[0:], unix.AF_INET6)
+	b[2], b[3] = byte(addr.Port>>8), byte(addr.Port)
+	copy(b[8:24], addr.IP.To16())
+
+	if addr.Zone != "" {
+		if ifi, err := net.InterfaceByName(addr.Zone); err == nil {
+			nativeEndian.PutUint32(b[24:], uint32(ifi.Index))
+		}
+	}
+
+	return b
+}
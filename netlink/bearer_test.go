@@ -0,0 +1,206 @@
+package netlink
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestParseBearerName(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		media string
+		ok    bool
+	}{
+		{"eth:eth0", "eth", true},
+		{"udp:bearer1", "udp", true},
+		{"ib:ib0", "ib", true},
+		{"eth0", "", false},
+		{":eth0", "", false},
+		{"eth:", "", false},
+		{"foo:eth0", "", false},
+		{"eth:averyveryverylongifname", "", false},
+	} {
+		media, err := parseBearerName(tt.name)
+		if ok := err == nil; ok != tt.ok || media != tt.media {
+			t.Errorf("parseBearerName(%q) = %q, %v", tt.name, media, err)
+		}
+	}
+}
+
+func TestBearerEnableRequest(t *testing.T) {
+	var got attrs
+
+	f := &fakeTransport{
+		handler: func(cmd uint8, flags uint16, b []byte) [][]byte {
+			if cmd != cmdBearerEnable || flags&unix.NLM_F_ACK == 0 {
+				t.Errorf("unexpected request cmd=%d flags=%#x", cmd, flags)
+			}
+
+			top, err := parseAttrs(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err = top.nested(attrBearer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return nil
+		},
+	}
+	defer withFake(f)()
+
+	err := BearerEnable("udp:b1", BearerOptions{
+		Domain:   0x1001000,
+		Priority: 10,
+		Local:    &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 6118},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name := got.str(attrBearerName); name != "udp:b1" {
+		t.Errorf("name = %q", name)
+	}
+
+	if d, _ := got.u32(attrBearerDomain); d != 0x1001000 {
+		t.Errorf("domain = %x", d)
+	}
+
+	prop, err := got.nested(attrBearerProp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p, _ := prop.u32(attrPropPriority); p != 10 {
+		t.Errorf("priority = %d", p)
+	}
+
+	udp, err := got.nested(attrBearerUDP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := udp[attrUDPLocal]
+	if len(local) != unix.SizeofSockaddrInet4 || local[2] != 0x17 || local[3] != 0xe6 || local[4] != 127 {
+		t.Errorf("local = %x", local)
+	}
+}
+
+func TestBearerEnableValidation(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts BearerOptions
+	}{
+		{"bogus", BearerOptions{}},
+		{"eth:eth0", BearerOptions{Priority: 32}},
+		{"udp:b1", BearerOptions{}},
+		{"eth:eth0", BearerOptions{Local: &net.UDPAddr{}}},
+	} {
+		if err := BearerEnable(tt.name, tt.opts); err == nil {
+			t.Errorf("BearerEnable(%q, %+v) succeeded", tt.name, tt.opts)
+		}
+	}
+}
+
+func TestBearerSetRequest(t *testing.T) {
+	var got attrs
+
+	f := &fakeTransport{
+		handler: func(cmd uint8, flags uint16, b []byte) [][]byte {
+			if cmd != cmdBearerSet || flags&unix.NLM_F_ACK == 0 {
+				t.Errorf("unexpected request cmd=%d flags=%#x", cmd, flags)
+			}
+
+			top, err := parseAttrs(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err = top.nested(attrBearer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			return nil
+		},
+	}
+	defer withFake(f)()
+
+	err := BearerSet("eth:eth0", BearerProps{Priority: 20, Tolerance: 1500 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if name := got.str(attrBearerName); name != "eth:eth0" {
+		t.Errorf("name = %q", name)
+	}
+
+	prop, err := got.nested(attrBearerProp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p, _ := prop.u32(attrPropPriority); p != 20 {
+		t.Errorf("priority = %d", p)
+	}
+
+	if tol, _ := prop.u32(attrPropTolerance); tol != 1500 {
+		t.Errorf("tolerance = %d", tol)
+	}
+
+	if _, ok := prop.u32(attrPropWindow); ok {
+		t.Error("window set although zero")
+	}
+}
+
+func TestBearerSetValidation(t *testing.T) {
+	for _, props := range []BearerProps{
+		{},
+		{Priority: 32},
+		{Tolerance: time.Millisecond},
+		{Tolerance: time.Minute},
+		{Window: 8},
+		{Window: 10000},
+	} {
+		if err := BearerSet("eth:eth0", props); err == nil {
+			t.Errorf("BearerSet(%+v) succeeded", props)
+		}
+	}
+
+	if err := BearerSet("bogus", BearerProps{Priority: 1}); err == nil {
+		t.Error("BearerSet accepted an invalid name")
+	}
+}
+
+func TestBearerEnableDisable(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires CAP_NET_ADMIN")
+	}
+
+	const name = "udp:tipctest"
+
+	err := BearerEnable(name, BearerOptions{
+		Local: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 6118},
+	})
+	if err == ErrFamilyNotFound {
+		t.Skip(err)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := BearerDisable(name); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := BearerDisable(name); err == nil {
+		t.Fatal("disabling a disabled bearer succeeded")
+	}
+}
@@ -0,0 +1,135 @@
+package netlink
+
+// Commands and attributes from linux/tipc_netlink.h.
+
+const (
+	cmdBearerDisable = 2
+	cmdBearerEnable  = 3
+	cmdBearerGet     = 4
+	cmdBearerSet     = 5
+	cmdSockGet       = 6
+	cmdPublGet       = 7
+	cmdLinkGet       = 8
+	cmdMediaGet      = 11
+	cmdNodeGet       = 13
+)
+
+// top level attributes
+const (
+	attrBearer = 1
+	attrSock   = 2
+	attrPubl   = 3
+	attrLink   = 4
+	attrMedia  = 5
+	attrNode   = 6
+)
+
+// SOCK_DIAG_BY_FAMILY from linux/sock_diag.h, the message type of socket
+// diagnostics requests and replies.
+const sockDiagByFamily = 20
+
+// TIPC_NLA_SOCK_*
+const (
+	attrSockAddr      = 1
+	attrSockRef       = 2
+	attrSockCon       = 3
+	attrSockHasPubl   = 4
+	attrSockStat      = 5
+	attrSockType      = 6
+	attrSockTIPCState = 9
+)
+
+// TIPC_NLA_CON_*
+const (
+	attrConFlag = 1
+	attrConNode = 2
+	attrConSock = 3
+	attrConType = 4
+	attrConInst = 5
+)
+
+// TIPC_NLA_SOCK_STAT_*
+const (
+	attrSockStatRcvQ     = 1
+	attrSockStatSendQ    = 2
+	attrSockStatLinkCong = 3
+	attrSockStatConnCong = 4
+	attrSockStatDrop     = 5
+)
+
+// TIPC_NLA_PUBL_*
+const (
+	attrPublType  = 1
+	attrPublLower = 2
+	attrPublUpper = 3
+	attrPublScope = 4
+)
+
+// TIPC_NLA_BEARER_*
+const (
+	attrBearerName   = 1
+	attrBearerProp   = 2
+	attrBearerDomain = 3
+	attrBearerUDP    = 4
+)
+
+// TIPC_NLA_UDP_*
+const (
+	attrUDPLocal  = 1
+	attrUDPRemote = 2
+)
+
+// TIPC_NLA_LINK_*
+const (
+	attrLinkName      = 1
+	attrLinkDest      = 2
+	attrLinkMTU       = 3
+	attrLinkBroadcast = 4
+	attrLinkUp        = 5
+	attrLinkActive    = 6
+	attrLinkProp      = 7
+	attrLinkStats     = 8
+	attrLinkRx        = 9
+	attrLinkTx        = 10
+)
+
+// TIPC_NLA_NODE_*
+const (
+	attrNodeAddr = 1
+	attrNodeUp   = 2
+	attrNodeID   = 3
+)
+
+// TIPC_NLA_PROP_*
+const (
+	attrPropPriority  = 1
+	attrPropTolerance = 2
+	attrPropWindow    = 3
+)
+
+// TIPC_NLA_STATS_*
+const (
+	attrStatsRxInfo        = 1
+	attrStatsRxFragments   = 2
+	attrStatsRxFragmented  = 3
+	attrStatsRxBundles     = 4
+	attrStatsRxBundled     = 5
+	attrStatsTxInfo        = 6
+	attrStatsTxFragments   = 7
+	attrStatsTxFragmented  = 8
+	attrStatsTxBundles     = 9
+	attrStatsTxBundled     = 10
+	attrStatsRxStates      = 21
+	attrStatsRxProbes      = 22
+	attrStatsRxNacks       = 23
+	attrStatsRxDeferred    = 24
+	attrStatsTxStates      = 25
+	attrStatsTxProbes      = 26
+	attrStatsTxNacks       = 27
+	attrStatsTxAcks        = 28
+	attrStatsRetransmitted = 29
+	attrStatsDuplicates    = 30
+	attrStatsLinkCongs     = 31
+	attrStatsMaxQueue      = 32
+	attrStatsAvgQueue      = 33
+)
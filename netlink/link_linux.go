@@ -0,0 +1,95 @@
+package netlink
+
+import (
+	"strings"
+)
+
+// Link describes a TIPC link to a peer node.
+type Link struct {
+	// Name is the link name, such as "1001001:eth0-1001002:eth0".
+	Name string
+
+	// Bearer is the local interface the link runs over, derived from the
+	// link name. It is empty for the broadcast link.
+	Bearer string
+
+	// Peer is the node address of the peer node.
+	Peer uint32
+
+	MTU       uint32
+	Up        bool
+	Active    bool
+	Broadcast bool
+}
+
+// Links returns the links of the local node, including the broadcast link.
+func Links() ([]Link, error) {
+	c, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.Close()
+
+	msgs, err := c.request(cmdLinkGet, dumpFlags, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]Link, 0, len(msgs))
+
+	for _, m := range msgs {
+		l, err := parseLink(m)
+		if err != nil {
+			return nil, err
+		}
+
+		links = append(links, l)
+	}
+
+	return links, nil
+}
+
+func parseLink(b []byte) (Link, error) {
+	top, err := parseAttrs(b)
+	if err != nil {
+		return Link{}, err
+	}
+
+	a, err := top.nested(attrLink)
+	if err != nil {
+		return Link{}, err
+	}
+
+	l := Link{
+		Name:      a.str(attrLinkName),
+		Up:        a.flag(attrLinkUp),
+		Active:    a.flag(attrLinkActive),
+		Broadcast: a.flag(attrLinkBroadcast),
+	}
+
+	l.Peer, _ = a.u32(attrLinkDest)
+	l.MTU, _ = a.u32(attrLinkMTU)
+
+	if !l.Broadcast {
+		l.Bearer = linkBearer(l.Name)
+	}
+
+	return l, nil
+}
+
+// linkBearer extracts the local interface from a link name of the form
+// "<self>:<interface>-<peer>:<peer interface>".
+func linkBearer(name string) string {
+	parts := strings.Split(name, ":")
+	if len(parts) != 3 {
+		return ""
+	}
+
+	i := strings.LastIndex(parts[1], "-")
+	if i < 0 {
+		return ""
+	}
+
+	return parts[1][:i]
+}
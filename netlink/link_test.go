@@ -0,0 +1,59 @@
+package netlink
+
+import (
+	"reflect"
+	"testing"
+)
+
+func linkAttrs(name string, dest uint32, broadcast, up bool) []byte {
+	var ab attrBuilder
+	ab.nested(attrLink, func(nb *attrBuilder) {
+		nb.str(attrLinkName, name)
+		nb.u32(attrLinkMTU, 1500)
+
+		if broadcast {
+			nb.flag(attrLinkBroadcast)
+		} else {
+			nb.u32(attrLinkDest, dest)
+		}
+
+		if up {
+			nb.flag(attrLinkUp)
+			nb.flag(attrLinkActive)
+		}
+	})
+
+	return ab.bytes()
+}
+
+func TestLinks(t *testing.T) {
+	f := &fakeTransport{
+		handler: func(cmd uint8, flags uint16, attrs []byte) [][]byte {
+			if cmd != cmdLinkGet || flags&dumpFlags != dumpFlags {
+				t.Errorf("unexpected request cmd=%d flags=%#x", cmd, flags)
+			}
+
+			return [][]byte{
+				linkAttrs("broadcast-link", 0, true, true),
+				linkAttrs("1001001:eth0-1001002:eth1", 0x1001002, false, true),
+				linkAttrs("1001001:veth-a-1001003:eth0", 0x1001003, false, false),
+			}
+		},
+	}
+	defer withFake(f)()
+
+	links, err := Links()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Link{
+		{Name: "broadcast-link", MTU: 1500, Up: true, Active: true, Broadcast: true},
+		{Name: "1001001:eth0-1001002:eth1", Bearer: "eth0", Peer: 0x1001002, MTU: 1500, Up: true, Active: true},
+		{Name: "1001001:veth-a-1001003:eth0", Bearer: "veth-a", Peer: 0x1001003, MTU: 1500},
+	}
+
+	if !reflect.DeepEqual(links, want) {
+		t.Fatalf("got %+v\nwant %+v", links, want)
+	}
+}
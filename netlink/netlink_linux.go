@@ -0,0 +1,404 @@
+// Package netlink implements the TIPC generic netlink management interface,
+// as used by the tipc tool, for inspecting and configuring the local node,
+// along with the TIPC socket diagnostics of NETLINK_SOCK_DIAG.
+package netlink
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	genlName    = "TIPCv2"
+	genlVersion = 1
+
+	dumpFlags = unix.NLM_F_DUMP
+
+	sizeofGenlmsghdr = int(unsafe.Sizeof(unix.Genlmsghdr{}))
+)
+
+// nativeEndian is the byte order of netlink messages.
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+
+	return binary.BigEndian
+}()
+
+// ErrFamilyNotFound is returned when the kernel does not provide the TIPC
+// generic netlink family, usually because the tipc module is not loaded.
+var ErrFamilyNotFound = errors.New("netlink: TIPC generic netlink family not found")
+
+var errEmptyResponse = errors.New("netlink: empty response")
+
+// transport sends and receives raw netlink messages.
+type transport interface {
+	Send(b []byte) error
+	Receive() ([]byte, error)
+	Close() error
+}
+
+type socketTransport struct {
+	fd  int
+	buf []byte
+}
+
+func dialSocket(proto int) (*socketTransport, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC, proto)
+	if err != nil {
+		return nil, os.NewSyscallError("socket", err)
+	}
+
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		unix.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+
+	return &socketTransport{fd: fd, buf: make([]byte, 64*1024)}, nil
+}
+
+func (t *socketTransport) Send(b []byte) error {
+	err := unix.Sendto(t.fd, b, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK})
+	if err != nil {
+		return os.NewSyscallError("sendto", err)
+	}
+
+	return nil
+}
+
+func (t *socketTransport) Receive() ([]byte, error) {
+	n, _, err := unix.Recvfrom(t.fd, t.buf, 0)
+	if err != nil {
+		return nil, os.NewSyscallError("recvfrom", err)
+	}
+
+	return t.buf[:n], nil
+}
+
+func (t *socketTransport) Close() error {
+	return unix.Close(t.fd)
+}
+
+// client issues requests to the TIPC generic netlink family, or, if diag is
+// set, to the TIPC socket diagnostics of NETLINK_SOCK_DIAG, whose messages
+// have no generic netlink header.
+type client struct {
+	t      transport
+	family uint16
+	seq    uint32
+	diag   bool
+}
+
+// dial and dialDiag are replaced in tests to use a fake transport.
+var (
+	dial = func() (transport, error) {
+		return dialSocket(unix.NETLINK_GENERIC)
+	}
+
+	dialDiag = func() (transport, error) {
+		return dialSocket(unix.NETLINK_SOCK_DIAG)
+	}
+)
+
+func newClient() (*client, error) {
+	t, err := dial()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &client{t: t}
+
+	if err := c.resolveFamily(); err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// newDiagClient returns a client for the TIPC socket diagnostics.
+func newDiagClient() (*client, error) {
+	t, err := dialDiag()
+	if err != nil {
+		return nil, err
+	}
+
+	return &client{t: t, family: sockDiagByFamily, diag: true}, nil
+}
+
+func (c *client) Close() error {
+	return c.t.Close()
+}
+
+func (c *client) resolveFamily() error {
+	var ab attrBuilder
+	ab.str(unix.CTRL_ATTR_FAMILY_NAME, genlName)
+
+	msgs, err := c.execute(unix.GENL_ID_CTRL, unix.CTRL_CMD_GETFAMILY, 0, ab.bytes())
+	if err != nil {
+		if err == unix.ENOENT {
+			return ErrFamilyNotFound
+		}
+
+		return err
+	}
+
+	if len(msgs) == 0 {
+		return errEmptyResponse
+	}
+
+	attrs, err := parseAttrs(msgs[0])
+	if err != nil {
+		return err
+	}
+
+	id, ok := attrs.u16(unix.CTRL_ATTR_FAMILY_ID)
+	if !ok {
+		return errors.New("netlink: family response missing id")
+	}
+
+	c.family = id
+
+	return nil
+}
+
+// request sends a TIPC command and returns the attributes of each response
+// message.
+func (c *client) request(cmd uint8, flags uint16, attrs []byte) ([][]byte, error) {
+	return c.execute(c.family, cmd, flags, attrs)
+}
+
+func (c *client) execute(family uint16, cmd uint8, flags uint16, attrs []byte) ([][]byte, error) {
+	c.seq++
+
+	hdrlen := c.hdrlen()
+
+	msg := make([]byte, unix.SizeofNlMsghdr+hdrlen+len(attrs))
+
+	hdr := (*unix.NlMsghdr)(unsafe.Pointer(&msg[0]))
+	hdr.Len = uint32(len(msg))
+	hdr.Type = family
+	hdr.Flags = unix.NLM_F_REQUEST | flags
+	hdr.Seq = c.seq
+
+	if hdrlen > 0 {
+		msg[unix.SizeofNlMsghdr] = cmd
+		msg[unix.SizeofNlMsghdr+1] = genlVersion
+	}
+
+	copy(msg[unix.SizeofNlMsghdr+hdrlen:], attrs)
+
+	if err := c.t.Send(msg); err != nil {
+		return nil, err
+	}
+
+	var out [][]byte
+
+	for {
+		b, err := c.t.Receive()
+		if err != nil {
+			return nil, err
+		}
+
+		done, err := c.parseMessages(b, &out)
+		if err != nil {
+			return nil, err
+		}
+
+		if done {
+			return out, nil
+		}
+	}
+}
+
+// parseMessages appends the generic netlink payloads in b to out, and reports
+// whether the response is complete.
+func (c *client) parseMessages(b []byte, out *[][]byte) (bool, error) {
+	for len(b) >= unix.SizeofNlMsghdr {
+		var hdr unix.NlMsghdr
+
+		hdr.Len = nativeEndian.Uint32(b[0:])
+		hdr.Type = nativeEndian.Uint16(b[4:])
+		hdr.Flags = nativeEndian.Uint16(b[6:])
+		hdr.Seq = nativeEndian.Uint32(b[8:])
+
+		if hdr.Len < unix.SizeofNlMsghdr || int(hdr.Len) > len(b) {
+			return false, errors.New("netlink: malformed message")
+		}
+
+		payload := b[unix.SizeofNlMsghdr:hdr.Len]
+		b = b[nlmsgAlign(int(hdr.Len)):]
+
+		if hdr.Seq != c.seq {
+			continue
+		}
+
+		switch hdr.Type {
+		case unix.NLMSG_DONE:
+			return true, nil
+		case unix.NLMSG_ERROR:
+			if len(payload) < 4 {
+				return false, errors.New("netlink: malformed error message")
+			}
+
+			if code := int32(nativeEndian.Uint32(payload)); code != 0 {
+				return false, unix.Errno(-code)
+			}
+
+			// An acknowledgement.
+			return true, nil
+		}
+
+		if len(payload) < c.hdrlen() {
+			return false, errors.New("netlink: short generic netlink message")
+		}
+
+		*out = append(*out, payload[c.hdrlen():])
+
+		if hdr.Flags&unix.NLM_F_MULTI == 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// hdrlen returns the size of the family header following the netlink header.
+func (c *client) hdrlen() int {
+	if c.diag {
+		return 0
+	}
+
+	return sizeofGenlmsghdr
+}
+
+func nlmsgAlign(n int) int {
+	return (n + unix.NLMSG_ALIGNTO - 1) &^ (unix.NLMSG_ALIGNTO - 1)
+}
+
+func nlaAlign(n int) int {
+	return (n + unix.NLA_ALIGNTO - 1) &^ (unix.NLA_ALIGNTO - 1)
+}
+
+// attrBuilder encodes netlink attributes.
+type attrBuilder struct {
+	b []byte
+}
+
+func (ab *attrBuilder) add(typ uint16, data []byte) {
+	n := unix.SizeofNlAttr + len(data)
+	hdr := make([]byte, unix.SizeofNlAttr)
+
+	nativeEndian.PutUint16(hdr[0:], uint16(n))
+	nativeEndian.PutUint16(hdr[2:], typ)
+
+	ab.b = append(ab.b, hdr...)
+	ab.b = append(ab.b, data...)
+	ab.b = append(ab.b, make([]byte, nlaAlign(n)-n)...)
+}
+
+func (ab *attrBuilder) u32(typ uint16, v uint32) {
+	b := make([]byte, 4)
+	nativeEndian.PutUint32(b, v)
+	ab.add(typ, b)
+}
+
+func (ab *attrBuilder) u16(typ uint16, v uint16) {
+	b := make([]byte, 2)
+	nativeEndian.PutUint16(b, v)
+	ab.add(typ, b)
+}
+
+func (ab *attrBuilder) str(typ uint16, s string) {
+	ab.add(typ, append([]byte(s), 0))
+}
+
+func (ab *attrBuilder) flag(typ uint16) {
+	ab.add(typ, nil)
+}
+
+func (ab *attrBuilder) nested(typ uint16, fn func(*attrBuilder)) {
+	var nb attrBuilder
+	fn(&nb)
+	ab.add(typ|unix.NLA_F_NESTED, nb.b)
+}
+
+func (ab *attrBuilder) bytes() []byte {
+	return ab.b
+}
+
+// attrs holds decoded netlink attributes by type.
+type attrs map[uint16][]byte
+
+func parseAttrs(b []byte) (attrs, error) {
+	a := make(attrs)
+
+	for len(b) >= unix.SizeofNlAttr {
+		n := int(nativeEndian.Uint16(b[0:]))
+		typ := nativeEndian.Uint16(b[2:]) &^ (unix.NLA_F_NESTED | unix.NLA_F_NET_BYTEORDER)
+
+		if n < unix.SizeofNlAttr || n > len(b) {
+			return nil, errors.New("netlink: malformed attribute")
+		}
+
+		a[typ] = b[unix.SizeofNlAttr:n]
+
+		if nlaAlign(n) >= len(b) {
+			break
+		}
+
+		b = b[nlaAlign(n):]
+	}
+
+	return a, nil
+}
+
+func (a attrs) u32(typ uint16) (uint32, bool) {
+	b, ok := a[typ]
+	if !ok || len(b) < 4 {
+		return 0, false
+	}
+
+	return nativeEndian.Uint32(b), true
+}
+
+func (a attrs) u16(typ uint16) (uint16, bool) {
+	b, ok := a[typ]
+	if !ok || len(b) < 2 {
+		return 0, false
+	}
+
+	return nativeEndian.Uint16(b), true
+}
+
+func (a attrs) str(typ uint16) string {
+	b := a[typ]
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+
+	return string(b)
+}
+
+func (a attrs) flag(typ uint16) bool {
+	_, ok := a[typ]
+	return ok
+}
+
+func (a attrs) nested(typ uint16) (attrs, error) {
+	b, ok := a[typ]
+	if !ok {
+		return nil, fmt.Errorf("netlink: missing attribute %d", typ)
+	}
+
+	return parseAttrs(b)
+}
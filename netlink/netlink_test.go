@@ -0,0 +1,131 @@
+package netlink
+
+import (
+	"testing"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const fakeFamily = 0x20
+
+// fakeTransport answers requests using handler, after resolving the TIPC
+// family itself.
+type fakeTransport struct {
+	handler func(cmd uint8, flags uint16, attrs []byte) [][]byte
+	reqs    [][]byte
+	pending [][]byte
+}
+
+func (f *fakeTransport) Send(b []byte) error {
+	hdr := (*unix.NlMsghdr)(unsafe.Pointer(&b[0]))
+	cmd := b[unix.SizeofNlMsghdr]
+	attrs := b[unix.SizeofNlMsghdr+sizeofGenlmsghdr:]
+
+	f.reqs = append(f.reqs, b)
+
+	if hdr.Type == unix.GENL_ID_CTRL {
+		var ab attrBuilder
+		ab.u16(unix.CTRL_ATTR_FAMILY_ID, fakeFamily)
+		ab.str(unix.CTRL_ATTR_FAMILY_NAME, genlName)
+		f.pending = append(f.pending, nlmsg(hdr.Type, 0, hdr.Seq, ab.bytes()))
+		return nil
+	}
+
+	for _, m := range f.handler(cmd, hdr.Flags, attrs) {
+		f.pending = append(f.pending, nlmsg(fakeFamily, unix.NLM_F_MULTI, hdr.Seq, m))
+	}
+
+	f.pending = append(f.pending, nlmsg(unix.NLMSG_DONE, unix.NLM_F_MULTI, hdr.Seq, nil))
+
+	return nil
+}
+
+func (f *fakeTransport) Receive() ([]byte, error) {
+	b := f.pending[0]
+	f.pending = f.pending[1:]
+	return b, nil
+}
+
+func (f *fakeTransport) Close() error {
+	return nil
+}
+
+// nlmsg builds a generic netlink message carrying attrs.
+func nlmsg(typ, flags uint16, seq uint32, attrs []byte) []byte {
+	b := make([]byte, unix.SizeofNlMsghdr+sizeofGenlmsghdr+len(attrs))
+
+	nativeEndian.PutUint32(b[0:], uint32(len(b)))
+	nativeEndian.PutUint16(b[4:], typ)
+	nativeEndian.PutUint16(b[6:], flags)
+	nativeEndian.PutUint32(b[8:], seq)
+	copy(b[unix.SizeofNlMsghdr+sizeofGenlmsghdr:], attrs)
+
+	return b
+}
+
+// withFake makes newClient use f, and returns a function restoring the real
+// transport.
+func withFake(f *fakeTransport) func() {
+	old := dial
+	dial = func() (transport, error) { return f, nil }
+	return func() { dial = old }
+}
+
+func TestAttrRoundTrip(t *testing.T) {
+	var ab attrBuilder
+	ab.str(1, "eth0")
+	ab.u32(2, 0x1001002)
+	ab.flag(3)
+	ab.nested(4, func(nb *attrBuilder) {
+		nb.u16(1, 7)
+	})
+
+	a, err := parseAttrs(ab.bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := a.str(1); s != "eth0" {
+		t.Errorf("str = %q", s)
+	}
+
+	if v, ok := a.u32(2); !ok || v != 0x1001002 {
+		t.Errorf("u32 = %x, %v", v, ok)
+	}
+
+	if !a.flag(3) || a.flag(5) {
+		t.Errorf("flags wrong")
+	}
+
+	n, err := a.nested(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v, ok := n.u16(1); !ok || v != 7 {
+		t.Errorf("nested u16 = %d, %v", v, ok)
+	}
+}
+
+func TestParseAttrsMalformed(t *testing.T) {
+	if _, err := parseAttrs([]byte{0xff, 0x00, 0x01, 0x00}); err == nil {
+		t.Fatal("expected error for oversized attribute")
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	c := &client{seq: 1}
+
+	b := make([]byte, unix.SizeofNlMsghdr+4)
+	nativeEndian.PutUint32(b[0:], uint32(len(b)))
+	nativeEndian.PutUint16(b[4:], unix.NLMSG_ERROR)
+	nativeEndian.PutUint32(b[8:], 1)
+	code := -int32(unix.EPERM)
+	nativeEndian.PutUint32(b[16:], uint32(code))
+
+	var out [][]byte
+	if _, err := c.parseMessages(b, &out); err != unix.EPERM {
+		t.Fatalf("got %v, want EPERM", err)
+	}
+}
@@ -0,0 +1,64 @@
+package netlink
+
+import (
+	"github.com/mischief/tipc"
+)
+
+// Node describes a node known to the local TIPC stack.
+type Node struct {
+	// Addr is the node's 32-bit address.
+	Addr uint32
+
+	// ID is the node's 128-bit identity, or zero if the kernel does not
+	// report it.
+	ID tipc.NodeID
+
+	// Up reports whether the node is currently reachable.
+	Up bool
+}
+
+// Nodes returns the nodes in the cluster, including the local node.
+func Nodes() ([]Node, error) {
+	c, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.Close()
+
+	msgs, err := c.request(cmdNodeGet, dumpFlags, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]Node, 0, len(msgs))
+
+	for _, m := range msgs {
+		n, err := parseNode(m)
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, n)
+	}
+
+	return nodes, nil
+}
+
+func parseNode(b []byte) (Node, error) {
+	top, err := parseAttrs(b)
+	if err != nil {
+		return Node{}, err
+	}
+
+	a, err := top.nested(attrNode)
+	if err != nil {
+		return Node{}, err
+	}
+
+	n := Node{Up: a.flag(attrNodeUp)}
+	n.Addr, _ = a.u32(attrNodeAddr)
+	copy(n.ID[:], a[attrNodeID])
+
+	return n, nil
+}
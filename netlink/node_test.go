@@ -0,0 +1,95 @@
+package netlink
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mischief/tipc"
+	"golang.org/x/sys/unix"
+)
+
+func nodeAttrs(addr uint32, id []byte, up bool) []byte {
+	var ab attrBuilder
+	ab.nested(attrNode, func(nb *attrBuilder) {
+		nb.u32(attrNodeAddr, addr)
+
+		if id != nil {
+			nb.add(attrNodeID, id)
+		}
+
+		if up {
+			nb.flag(attrNodeUp)
+		}
+	})
+
+	return ab.bytes()
+}
+
+func TestNodes(t *testing.T) {
+	f := &fakeTransport{
+		handler: func(cmd uint8, flags uint16, attrs []byte) [][]byte {
+			if cmd != cmdNodeGet || flags&dumpFlags != dumpFlags {
+				t.Errorf("unexpected request cmd=%d flags=%#x", cmd, flags)
+			}
+
+			return [][]byte{
+				nodeAttrs(0x1001001, nil, true),
+				nodeAttrs(0x1001002, []byte("node2"), false),
+			}
+		},
+	}
+	defer withFake(f)()
+
+	nodes, err := Nodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var id tipc.NodeID
+	copy(id[:], "node2")
+
+	want := []Node{
+		{Addr: 0x1001001, Up: true},
+		{Addr: 0x1001002, ID: id},
+	}
+
+	if !reflect.DeepEqual(nodes, want) {
+		t.Fatalf("got %+v\nwant %+v", nodes, want)
+	}
+}
+
+func TestMultipartBatch(t *testing.T) {
+	c := &client{seq: 7}
+
+	var b []byte
+	b = append(b, nlmsg(fakeFamily, unix.NLM_F_MULTI, 7, nodeAttrs(1, nil, true))...)
+	b = append(b, nlmsg(fakeFamily, unix.NLM_F_MULTI, 6, nodeAttrs(9, nil, true))...)
+	b = append(b, nlmsg(fakeFamily, unix.NLM_F_MULTI, 7, nodeAttrs(2, nil, false))...)
+
+	var out [][]byte
+
+	done, err := c.parseMessages(b, &out)
+	if err != nil || done {
+		t.Fatalf("parseMessages = %v, %v", done, err)
+	}
+
+	done, err = c.parseMessages(nlmsg(unix.NLMSG_DONE, unix.NLM_F_MULTI, 7, nil), &out)
+	if err != nil || !done {
+		t.Fatalf("parseMessages = %v, %v", done, err)
+	}
+
+	if len(out) != 2 {
+		t.Fatalf("got %d messages, want 2", len(out))
+	}
+
+	for i, m := range out {
+		n, err := parseNode(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n.Addr != uint32(i+1) {
+			t.Errorf("message %d: addr %d", i, n.Addr)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package netlink
+
+import (
+	"fmt"
+
+	"github.com/mischief/tipc"
+	"golang.org/x/sys/unix"
+)
+
+// SocketState is the connection state of a TIPC socket.
+type SocketState uint32
+
+// Socket states, as reported by the kernel.
+const (
+	SocketEstablished   SocketState = 1
+	SocketConnecting    SocketState = 2
+	SocketOpen          SocketState = 7
+	SocketDisconnecting SocketState = 8
+	SocketListening     SocketState = 10
+)
+
+func (s SocketState) String() string {
+	switch s {
+	case SocketEstablished:
+		return "established"
+	case SocketConnecting:
+		return "connecting"
+	case SocketOpen:
+		return "open"
+	case SocketDisconnecting:
+		return "disconnecting"
+	case SocketListening:
+		return "listening"
+	}
+
+	return fmt.Sprintf("SocketState(%d)", uint32(s))
+}
+
+// SocketPeer identifies the peer of a connected socket.
+type SocketPeer struct {
+	// Node and Ref are the node address and port number of the peer
+	// socket.
+	Node uint32
+	Ref  uint32
+
+	// Service is the service name the connection was made to, or nil if
+	// it was made to the peer's port identity.
+	Service *unix.TIPCServiceName
+}
+
+// SocketInfo describes a TIPC socket on the local node.
+type SocketInfo struct {
+	// Ref is the port number of the socket, and Node the address of the
+	// local node, which together form the socket's port identity.
+	Ref  uint32
+	Node uint32
+
+	// Type is the socket type, such as unix.SOCK_STREAM, and State its
+	// connection state. Both are zero if the kernel lacks TIPC socket
+	// diagnostics.
+	Type  int
+	State SocketState
+
+	// Services are the service ranges the socket is bound to.
+	Services []tipc.Binding
+
+	// Peer is the peer of a connected socket, or nil.
+	Peer *SocketPeer
+
+	// RecvQueue and SendQueue are the number of messages queued for
+	// reading and waiting to be sent, and Drops the number of messages
+	// dropped for lack of receive buffer space.
+	RecvQueue uint32
+	SendQueue uint32
+	Drops     uint32
+
+	// LinkCongested and ConnCongested report whether sends on the socket
+	// are held back by a congested link or by the connection's flow
+	// control.
+	LinkCongested bool
+	ConnCongested bool
+}
+
+// LocalSockets returns the TIPC sockets on the local node, like the tipc
+// socket list command. It requires no privileges, but only lists the sockets
+// of other users if the caller may see them.
+//
+// The sockets are listed through the TIPC socket diagnostics of
+// NETLINK_SOCK_DIAG, which report queue depths, type and state. Kernels
+// without them are queried through generic netlink instead, which only
+// reports the port identity, peer and services of each socket.
+func LocalSockets() ([]SocketInfo, error) {
+	msgs, err := diagSockets()
+	if err == unix.ENOENT {
+		msgs, err = genlSockets()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	socks := make([]SocketInfo, 0, len(msgs))
+
+	for _, m := range msgs {
+		s, publ, err := parseSocket(m)
+		if err != nil {
+			return nil, err
+		}
+
+		if publ {
+			if s.Services, err = publications(s.Ref); err != nil {
+				return nil, err
+			}
+		}
+
+		socks = append(socks, s)
+	}
+
+	return socks, nil
+}
+
+// diagSockets dumps the sockets through NETLINK_SOCK_DIAG.
+func diagSockets() ([][]byte, error) {
+	c, err := newDiagClient()
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.Close()
+
+	// struct tipc_sock_diag_req, selecting sockets in any state.
+	req := make([]byte, 8)
+	req[0] = unix.AF_TIPC
+	nativeEndian.PutUint32(req[4:], ^uint32(0))
+
+	return c.request(0, dumpFlags, req)
+}
+
+// genlSockets dumps the sockets through generic netlink.
+func genlSockets() ([][]byte, error) {
+	c, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.Close()
+
+	return c.request(cmdSockGet, dumpFlags, nil)
+}
+
+// publications returns the service ranges bound by the socket ref.
+func publications(ref uint32) ([]tipc.Binding, error) {
+	c, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.Close()
+
+	var ab attrBuilder
+	ab.nested(attrSock, func(nb *attrBuilder) {
+		nb.u32(attrSockRef, ref)
+	})
+
+	msgs, err := c.request(cmdPublGet, dumpFlags, ab.bytes())
+	if err == unix.EINVAL {
+		// The socket was closed since it was listed.
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	bindings := make([]tipc.Binding, 0, len(msgs))
+
+	for _, m := range msgs {
+		b, err := parsePublication(m)
+		if err != nil {
+			return nil, err
+		}
+
+		bindings = append(bindings, b)
+	}
+
+	return bindings, nil
+}
+
+// parseSocket decodes a socket description, as sent by both the socket
+// diagnostics and generic netlink, and reports whether the socket has bound
+// services to look up.
+func parseSocket(b []byte) (SocketInfo, bool, error) {
+	top, err := parseAttrs(b)
+	if err != nil {
+		return SocketInfo{}, false, err
+	}
+
+	a, err := top.nested(attrSock)
+	if err != nil {
+		return SocketInfo{}, false, err
+	}
+
+	var s SocketInfo
+	s.Ref, _ = a.u32(attrSockRef)
+	s.Node, _ = a.u32(attrSockAddr)
+
+	typ, _ := a.u32(attrSockType)
+	s.Type = int(typ)
+
+	state, _ := a.u32(attrSockTIPCState)
+	s.State = SocketState(state)
+
+	if a.flag(attrSockCon) {
+		con, err := a.nested(attrSockCon)
+		if err != nil {
+			return SocketInfo{}, false, err
+		}
+
+		p := &SocketPeer{}
+		p.Node, _ = con.u32(attrConNode)
+		p.Ref, _ = con.u32(attrConSock)
+
+		if con.flag(attrConFlag) {
+			p.Service = &unix.TIPCServiceName{}
+			p.Service.Type, _ = con.u32(attrConType)
+			p.Service.Instance, _ = con.u32(attrConInst)
+		}
+
+		s.Peer = p
+	}
+
+	if a.flag(attrSockStat) {
+		stat, err := a.nested(attrSockStat)
+		if err != nil {
+			return SocketInfo{}, false, err
+		}
+
+		s.RecvQueue, _ = stat.u32(attrSockStatRcvQ)
+		s.SendQueue, _ = stat.u32(attrSockStatSendQ)
+		s.Drops, _ = stat.u32(attrSockStatDrop)
+		s.LinkCongested = stat.flag(attrSockStatLinkCong)
+		s.ConnCongested = stat.flag(attrSockStatConnCong)
+	}
+
+	return s, a.flag(attrSockHasPubl), nil
+}
+
+func parsePublication(b []byte) (tipc.Binding, error) {
+	top, err := parseAttrs(b)
+	if err != nil {
+		return tipc.Binding{}, err
+	}
+
+	a, err := top.nested(attrPubl)
+	if err != nil {
+		return tipc.Binding{}, err
+	}
+
+	r := &unix.TIPCServiceRange{}
+	r.Type, _ = a.u32(attrPublType)
+	r.Lower, _ = a.u32(attrPublLower)
+	r.Upper, _ = a.u32(attrPublUpper)
+
+	scope, _ := a.u32(attrPublScope)
+
+	return tipc.Binding{Scope: int(scope), Range: r}, nil
+}
@@ -0,0 +1,248 @@
+package netlink
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+
+	"github.com/mischief/tipc"
+	"golang.org/x/sys/unix"
+)
+
+// diagTransport answers a socket diagnostics dump with msgs, or with err.
+type diagTransport struct {
+	msgs    [][]byte
+	err     unix.Errno
+	req     []byte
+	pending [][]byte
+}
+
+func (d *diagTransport) Send(b []byte) error {
+	hdr := (*unix.NlMsghdr)(unsafe.Pointer(&b[0]))
+
+	d.req = b[unix.SizeofNlMsghdr:]
+
+	if d.err != 0 {
+		code := make([]byte, 4)
+		nativeEndian.PutUint32(code, uint32(-int32(d.err)))
+		d.pending = append(d.pending, diagMsg(unix.NLMSG_ERROR, 0, hdr.Seq, code))
+		return nil
+	}
+
+	for _, m := range d.msgs {
+		d.pending = append(d.pending, diagMsg(sockDiagByFamily, unix.NLM_F_MULTI, hdr.Seq, m))
+	}
+
+	d.pending = append(d.pending, diagMsg(unix.NLMSG_DONE, unix.NLM_F_MULTI, hdr.Seq, nil))
+
+	return nil
+}
+
+func (d *diagTransport) Receive() ([]byte, error) {
+	b := d.pending[0]
+	d.pending = d.pending[1:]
+	return b, nil
+}
+
+func (d *diagTransport) Close() error {
+	return nil
+}
+
+// diagMsg builds a netlink message without a generic netlink header.
+func diagMsg(typ, flags uint16, seq uint32, payload []byte) []byte {
+	b := make([]byte, unix.SizeofNlMsghdr+len(payload))
+
+	nativeEndian.PutUint32(b[0:], uint32(len(b)))
+	nativeEndian.PutUint16(b[4:], typ)
+	nativeEndian.PutUint16(b[6:], flags)
+	nativeEndian.PutUint32(b[8:], seq)
+	copy(b[unix.SizeofNlMsghdr:], payload)
+
+	return b
+}
+
+func withFakeDiag(d *diagTransport) func() {
+	old := dialDiag
+	dialDiag = func() (transport, error) { return d, nil }
+	return func() { dialDiag = old }
+}
+
+// listenerAttrs describes a listening stream socket with bound services and
+// queued messages, as sent by the socket diagnostics.
+func listenerAttrs(ref uint32) []byte {
+	var ab attrBuilder
+	ab.nested(attrSock, func(nb *attrBuilder) {
+		nb.u32(attrSockAddr, 0x1001001)
+		nb.u32(attrSockRef, ref)
+		nb.u32(attrSockType, unix.SOCK_STREAM)
+		nb.u32(attrSockTIPCState, uint32(SocketListening))
+		nb.nested(attrSockStat, func(sb *attrBuilder) {
+			sb.u32(attrSockStatRcvQ, 3)
+			sb.u32(attrSockStatSendQ, 0)
+			sb.u32(attrSockStatDrop, 1)
+			sb.flag(attrSockStatLinkCong)
+		})
+		nb.flag(attrSockHasPubl)
+	})
+
+	return ab.bytes()
+}
+
+// connAttrs describes a socket connected to {1000, 7} on port 42 of node
+// 0x1001002, as sent by generic netlink.
+func connAttrs(ref uint32) []byte {
+	var ab attrBuilder
+	ab.nested(attrSock, func(nb *attrBuilder) {
+		nb.u32(attrSockAddr, 0x1001001)
+		nb.u32(attrSockRef, ref)
+		nb.nested(attrSockCon, func(cb *attrBuilder) {
+			cb.flag(attrConFlag)
+			cb.u32(attrConNode, 0x1001002)
+			cb.u32(attrConSock, 42)
+			cb.u32(attrConType, 1000)
+			cb.u32(attrConInst, 7)
+		})
+	})
+
+	return ab.bytes()
+}
+
+func publAttrs(typ, lower, upper, scope uint32) []byte {
+	var ab attrBuilder
+	ab.nested(attrPubl, func(nb *attrBuilder) {
+		nb.u32(attrPublType, typ)
+		nb.u32(attrPublLower, lower)
+		nb.u32(attrPublUpper, upper)
+		nb.u32(attrPublScope, scope)
+	})
+
+	return ab.bytes()
+}
+
+func TestParseSocket(t *testing.T) {
+	s, publ, err := parseSocket(listenerAttrs(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := SocketInfo{
+		Ref:           10,
+		Node:          0x1001001,
+		Type:          unix.SOCK_STREAM,
+		State:         SocketListening,
+		RecvQueue:     3,
+		Drops:         1,
+		LinkCongested: true,
+	}
+
+	if !publ || !reflect.DeepEqual(s, want) {
+		t.Errorf("got %+v, %v\nwant %+v, true", s, publ, want)
+	}
+
+	s, publ, err = parseSocket(connAttrs(11))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = SocketInfo{
+		Ref:  11,
+		Node: 0x1001001,
+		Peer: &SocketPeer{
+			Node:    0x1001002,
+			Ref:     42,
+			Service: &unix.TIPCServiceName{Type: 1000, Instance: 7},
+		},
+	}
+
+	if publ || !reflect.DeepEqual(s, want) {
+		t.Errorf("got %+v, %v\nwant %+v, false", s, publ, want)
+	}
+
+	if _, _, err := parseSocket([]byte{1, 2, 3, 4, 5}); err == nil {
+		t.Error("parseSocket accepted malformed attributes")
+	}
+}
+
+func TestLocalSockets(t *testing.T) {
+	d := &diagTransport{msgs: [][]byte{listenerAttrs(10)}}
+	defer withFakeDiag(d)()
+
+	f := &fakeTransport{
+		handler: func(cmd uint8, flags uint16, b []byte) [][]byte {
+			if cmd != cmdPublGet || flags&dumpFlags != dumpFlags {
+				t.Errorf("unexpected request cmd=%d flags=%#x", cmd, flags)
+			}
+
+			top, err := parseAttrs(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sock, err := top.nested(attrSock)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ref, _ := sock.u32(attrSockRef); ref != 10 {
+				t.Errorf("publications requested for ref %d", ref)
+			}
+
+			return [][]byte{
+				publAttrs(1000, 0, 9, unix.TIPC_CLUSTER_SCOPE),
+				publAttrs(1001, 5, 5, unix.TIPC_NODE_SCOPE),
+			}
+		},
+	}
+	defer withFake(f)()
+
+	socks, err := LocalSockets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.req) != 8 || d.req[0] != unix.AF_TIPC || nativeEndian.Uint32(d.req[4:]) != ^uint32(0) {
+		t.Errorf("diag request = %x", d.req)
+	}
+
+	if len(socks) != 1 {
+		t.Fatalf("got %d sockets, want 1", len(socks))
+	}
+
+	want := []tipc.Binding{
+		{Scope: unix.TIPC_CLUSTER_SCOPE, Range: &unix.TIPCServiceRange{Type: 1000, Lower: 0, Upper: 9}},
+		{Scope: unix.TIPC_NODE_SCOPE, Range: &unix.TIPCServiceRange{Type: 1001, Lower: 5, Upper: 5}},
+	}
+
+	if !reflect.DeepEqual(socks[0].Services, want) {
+		t.Errorf("services = %+v, want %+v", socks[0].Services, want)
+	}
+
+	if socks[0].RecvQueue != 3 || socks[0].State != SocketListening {
+		t.Errorf("socket = %+v", socks[0])
+	}
+}
+
+func TestLocalSocketsFallback(t *testing.T) {
+	d := &diagTransport{err: unix.ENOENT}
+	defer withFakeDiag(d)()
+
+	f := &fakeTransport{
+		handler: func(cmd uint8, flags uint16, b []byte) [][]byte {
+			if cmd != cmdSockGet {
+				t.Errorf("unexpected request cmd=%d", cmd)
+			}
+
+			return [][]byte{connAttrs(11)}
+		},
+	}
+	defer withFake(f)()
+
+	socks, err := LocalSockets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(socks) != 1 || socks[0].Ref != 11 || socks[0].Peer == nil || socks[0].Type != 0 {
+		t.Errorf("got %+v", socks)
+	}
+}
@@ -0,0 +1,115 @@
+package netlink
+
+// LinkStatistics holds the counters of a link, as reported by
+// "tipc link stat show".
+type LinkStatistics struct {
+	// RxPackets and TxPackets count the data messages received and sent
+	// on the link, excluding link protocol messages.
+	RxPackets uint32
+	TxPackets uint32
+
+	RxFragments  uint32
+	RxFragmented uint32
+	RxBundles    uint32
+	RxBundled    uint32
+	TxFragments  uint32
+	TxFragmented uint32
+	TxBundles    uint32
+	TxBundled    uint32
+
+	// Link protocol messages: state messages, probes, negative and
+	// positive acknowledgements.
+	RxStates   uint32
+	RxProbes   uint32
+	RxNacks    uint32
+	RxDeferred uint32
+	TxStates   uint32
+	TxProbes   uint32
+	TxNacks    uint32
+	TxAcks     uint32
+
+	Retransmitted uint32
+	Duplicates    uint32
+
+	// Congestions counts the times the link's send queue was congested.
+	Congestions uint32
+
+	// MaxQueue and AvgQueue are the maximum and average length of the send
+	// queue.
+	MaxQueue uint32
+	AvgQueue uint32
+}
+
+// LinkStats returns the statistics of the named link, as listed by Links.
+func LinkStats(name string) (*LinkStatistics, error) {
+	c, err := newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	defer c.Close()
+
+	var ab attrBuilder
+	ab.nested(attrLink, func(nb *attrBuilder) {
+		nb.str(attrLinkName, name)
+	})
+
+	msgs, err := c.request(cmdLinkGet, 0, ab.bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(msgs) == 0 {
+		return nil, errEmptyResponse
+	}
+
+	return parseLinkStats(msgs[0])
+}
+
+func parseLinkStats(b []byte) (*LinkStatistics, error) {
+	top, err := parseAttrs(b)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := top.nested(attrLink)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := link.nested(attrLinkStats)
+	if err != nil {
+		return nil, err
+	}
+
+	u32 := func(a attrs, typ uint16) uint32 {
+		v, _ := a.u32(typ)
+		return v
+	}
+
+	return &LinkStatistics{
+		RxPackets:     u32(link, attrLinkRx) - u32(a, attrStatsRxInfo),
+		TxPackets:     u32(link, attrLinkTx) - u32(a, attrStatsTxInfo),
+		RxFragments:   u32(a, attrStatsRxFragments),
+		RxFragmented:  u32(a, attrStatsRxFragmented),
+		RxBundles:     u32(a, attrStatsRxBundles),
+		RxBundled:     u32(a, attrStatsRxBundled),
+		TxFragments:   u32(a, attrStatsTxFragments),
+		TxFragmented:  u32(a, attrStatsTxFragmented),
+		TxBundles:     u32(a, attrStatsTxBundles),
+		TxBundled:     u32(a, attrStatsTxBundled),
+		RxStates:      u32(a, attrStatsRxStates),
+		RxProbes:      u32(a, attrStatsRxProbes),
+		RxNacks:       u32(a, attrStatsRxNacks),
+		RxDeferred:    u32(a, attrStatsRxDeferred),
+		TxStates:      u32(a, attrStatsTxStates),
+		TxProbes:      u32(a, attrStatsTxProbes),
+		TxNacks:       u32(a, attrStatsTxNacks),
+		TxAcks:        u32(a, attrStatsTxAcks),
+		Retransmitted: u32(a, attrStatsRetransmitted),
+		Duplicates:    u32(a, attrStatsDuplicates),
+		Congestions:   u32(a, attrStatsLinkCongs),
+		MaxQueue:      u32(a, attrStatsMaxQueue),
+		AvgQueue:      u32(a, attrStatsAvgQueue),
+	}, nil
+}
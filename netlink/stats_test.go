@@ -0,0 +1,128 @@
+package netlink
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// linkStatsReply is a TIPC_NL_LINK_GET reply for a single link, as sent by a
+// little-endian kernel in response to request sequence number 2.
+const linkStatsReply = "" +
+	"3801000020000000020000000000000000000000240104801e00010031303031" +
+	"3030313a657468302d313030313030323a657468300000000800020002100001" +
+	"08000300aa050000080009001b29000008000a006b2800000400050004000600" +
+	"1c000780080001000a00000008000200dc0500000800030032000000bc000880" +
+	"080001000b020000080002000c00000008000300030000000800040028000000" +
+	"08000500a0000000080006005b01000008000700080000000800080002000000" +
+	"080009002300000008000a008c00000008001500e00100000800160014000000" +
+	"08001700010000000800180004000000080019003601000008001a0016000000" +
+	"08001b000200000008001c000f00000008001d000600000008001e0003000000" +
+	"08001f000100000008002000400000000800210005000000"
+
+var wantLinkStats = &LinkStatistics{
+	RxPackets:     10000,
+	TxPackets:     10000,
+	RxFragments:   12,
+	RxFragmented:  3,
+	RxBundles:     40,
+	RxBundled:     160,
+	TxFragments:   8,
+	TxFragmented:  2,
+	TxBundles:     35,
+	TxBundled:     140,
+	RxStates:      480,
+	RxProbes:      20,
+	RxNacks:       1,
+	RxDeferred:    4,
+	TxStates:      310,
+	TxProbes:      22,
+	TxNacks:       2,
+	TxAcks:        15,
+	Retransmitted: 6,
+	Duplicates:    3,
+	Congestions:   1,
+	MaxQueue:      64,
+	AvgQueue:      5,
+}
+
+func TestParseLinkStats(t *testing.T) {
+	if nativeEndian != binary.LittleEndian {
+		t.Skip("fixture was captured on a little-endian host")
+	}
+
+	b, err := hex.DecodeString(linkStatsReply)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &client{seq: 2}
+
+	var msgs [][]byte
+
+	done, err := c.parseMessages(b, &msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !done || len(msgs) != 1 {
+		t.Fatalf("parseMessages = %v with %d messages", done, len(msgs))
+	}
+
+	stats, err := parseLinkStats(msgs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(stats, wantLinkStats) {
+		t.Errorf("got %+v\nwant %+v", stats, wantLinkStats)
+	}
+}
+
+func TestLinkStatsRequest(t *testing.T) {
+	if nativeEndian != binary.LittleEndian {
+		t.Skip("fixture was captured on a little-endian host")
+	}
+
+	reply, err := hex.DecodeString(linkStatsReply)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeTransport{
+		handler: func(cmd uint8, flags uint16, b []byte) [][]byte {
+			if cmd != cmdLinkGet || flags&dumpFlags != 0 {
+				t.Errorf("unexpected request cmd=%d flags=%#x", cmd, flags)
+			}
+
+			top, err := parseAttrs(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			link, err := top.nested(attrLink)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if name := link.str(attrLinkName); name != "1001001:eth0-1001002:eth0" {
+				t.Errorf("requested link %q", name)
+			}
+
+			return [][]byte{reply[unix.SizeofNlMsghdr+sizeofGenlmsghdr:]}
+		},
+	}
+	defer withFake(f)()
+
+	stats, err := LinkStats("1001001:eth0-1001002:eth0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(stats, wantLinkStats) {
+		t.Errorf("got %+v\nwant %+v", stats, wantLinkStats)
+	}
+}
@@ -0,0 +1,40 @@
+package tipc
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// NodeAddr returns the 32-bit address of the local node, as used in the Node
+// field of a socket address and the Domain field of a service name.
+//
+// This is the legacy node address, not the 128-bit node identity used by
+// kernels since 4.17. On those kernels, if no address has been configured,
+// the value returned is a hash derived from the node identity.
+func NodeAddr() (uint32, error) {
+	fd, err := socket(unix.SOCK_RDM)
+	if err != nil {
+		return 0, err
+	}
+
+	defer unix.Close(fd)
+
+	sa, err := unix.Getsockname(fd)
+	if err != nil {
+		return 0, os.NewSyscallError("getsockname", err)
+	}
+
+	ta, ok := sa.(*unix.SockaddrTIPC)
+	if !ok {
+		return 0, fmt.Errorf("tipc: unexpected socket address %T", sa)
+	}
+
+	id, ok := ta.Addr.(*unix.TIPCSocketAddr)
+	if !ok {
+		return 0, fmt.Errorf("tipc: unexpected socket address %T", ta.Addr)
+	}
+
+	return id.Node, nil
+}
@@ -0,0 +1,120 @@
+package tipc
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// PacketConn is a connectionless TIPC socket, of type SOCK_DGRAM or
+// SOCK_RDM, that implements net.PacketConn.
+type PacketConn struct {
+	conn *Conn
+}
+
+// NewPacketConn returns a PacketConn using c, which must be a connectionless
+// socket such as those returned by ListenDatagram or ReliableDatagram. The
+// PacketConn takes ownership of c.
+func NewPacketConn(c *Conn) *PacketConn {
+	return &PacketConn{conn: c}
+}
+
+// ListenPacket creates a connectionless socket on the named network, which
+// must be "tipc-dgram" or "tipc-rdm", and binds it to addr. addr is a
+// service range or name with its scope; if addr is nil the socket is left
+// unbound, and is reachable only at its port identity.
+func ListenPacket(network string, addr *Addr) (net.PacketConn, error) {
+	var (
+		typ int
+		sa  *unix.SockaddrTIPC
+		na  net.Addr
+	)
+
+	listenError := func(err error) error {
+		return &net.OpError{Op: "listen", Net: network, Addr: na, Err: err}
+	}
+
+	if addr != nil {
+		na = addr
+	}
+
+	switch network {
+	case "tipc-dgram":
+		typ = unix.SOCK_DGRAM
+	case "tipc-rdm":
+		typ = unix.SOCK_RDM
+	default:
+		return nil, listenError(net.UnknownNetworkError(network))
+	}
+
+	if addr != nil {
+		var ok bool
+		if sa, ok = addr.Sockaddr.(*unix.SockaddrTIPC); !ok {
+			return nil, listenError(&net.AddrError{Err: "expected tipc sockaddr", Addr: fmt.Sprintf("%T", addr.Sockaddr)})
+		}
+	}
+
+	c, err := newPacketConn(typ, sa, sa != nil)
+	if err != nil {
+		return nil, listenError(err)
+	}
+
+	return NewPacketConn(c), nil
+}
+
+// ReadFrom reads a message into p, returning the number of bytes read and
+// the address of the sending socket. Messages sent from this socket that
+// could not be delivered are reported as a *RejectedError.
+func (pc *PacketConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	n, addr, err := pc.conn.ReadFrom(p)
+	if err != nil {
+		if _, ok := err.(*RejectedError); ok {
+			return 0, addr, err
+		}
+
+		return 0, nil, pc.conn.opError("read", err)
+	}
+
+	return n, addr, nil
+}
+
+// WriteTo sends p as a single message to addr, which must be an *Addr.
+func (pc *PacketConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	if addr == nil {
+		return 0, &net.OpError{Op: "write", Net: opNetwork(pc.conn.typ), Source: pc.LocalAddr(), Err: errMissingAddress}
+	}
+
+	n, err := pc.conn.WriteTo(p, addr)
+	if err != nil {
+		switch err.(type) {
+		case *net.AddrError, *net.OpError:
+			return 0, err
+		}
+
+		return 0, &net.OpError{Op: "write", Net: opNetwork(pc.conn.typ), Source: pc.LocalAddr(), Addr: addr, Err: err}
+	}
+
+	return n, nil
+}
+
+func (pc *PacketConn) Close() error {
+	return pc.conn.Close()
+}
+
+func (pc *PacketConn) LocalAddr() net.Addr {
+	return pc.conn.LocalAddr()
+}
+
+func (pc *PacketConn) SetDeadline(t time.Time) error {
+	return pc.conn.SetDeadline(t)
+}
+
+func (pc *PacketConn) SetReadDeadline(t time.Time) error {
+	return pc.conn.SetReadDeadline(t)
+}
+
+func (pc *PacketConn) SetWriteDeadline(t time.Time) error {
+	return pc.conn.SetWriteDeadline(t)
+}
@@ -0,0 +1,248 @@
+package tipc
+
+import (
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// MessageConn is a connection that preserves message boundaries: each Read
+// or ReadMessage returns a single message written by the peer with one
+// Write. It is satisfied by *Conn for SOCK_SEQPACKET connections, such as
+// those made by SocketPair and DialSequentialPacket, and by the in-memory
+// connections made by Pipe, so that code written against MessageConn can be
+// tested without the TIPC kernel module.
+type MessageConn interface {
+	net.Conn
+
+	// ReadMessage reads a single message into p. If the message is longer
+	// than p, the rest of it is discarded and truncated is true.
+	ReadMessage(p []byte) (n int, truncated bool, err error)
+}
+
+// pipeQueueLen is the number of messages a Pipe connection queues before
+// Write waits for the peer to read.
+const pipeQueueLen = 64
+
+// pipePort numbers the ends of Pipe connections, accessed atomically.
+var pipePort uint32
+
+// Pipe returns the two ends of an in-memory connection that behaves like a
+// pair of connected SOCK_SEQPACKET sockets. Messages are delivered in order
+// with their boundaries intact, deadlines are honored, and once one end is
+// closed the other reads the messages still queued and then io.EOF. The ends
+// have distinct port identities on node 0, which no real socket uses.
+//
+// Pipe does not need TIPC and is meant for testing code written against
+// MessageConn.
+func Pipe() (MessageConn, MessageConn) {
+	c1to2 := make(chan []byte, pipeQueueLen)
+	c2to1 := make(chan []byte, pipeQueueLen)
+
+	p1 := newPipeConn(c2to1, c1to2)
+	p2 := newPipeConn(c1to2, c2to1)
+
+	p1.peer, p2.peer = p2, p1
+
+	return p1, p2
+}
+
+type pipeConn struct {
+	rx   <-chan []byte
+	tx   chan<- []byte
+	addr *Addr
+	peer *pipeConn
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	rdeadline pipeDeadline
+	wdeadline pipeDeadline
+}
+
+func newPipeConn(rx <-chan []byte, tx chan<- []byte) *pipeConn {
+	port := atomic.AddUint32(&pipePort, 1)
+
+	return &pipeConn{
+		rx: rx,
+		tx: tx,
+		addr: &Addr{
+			Sockaddr: &unix.SockaddrTIPC{Addr: &unix.TIPCSocketAddr{Ref: port}},
+			Net:      socketNetwork(unix.SOCK_SEQPACKET),
+		},
+		done:      make(chan struct{}),
+		rdeadline: makePipeDeadline(),
+		wdeadline: makePipeDeadline(),
+	}
+}
+
+func (pc *pipeConn) opError(op string, err error) error {
+	return &net.OpError{Op: op, Net: pc.addr.Network(), Source: pc.addr, Addr: pc.peer.addr, Err: err}
+}
+
+func (pc *pipeConn) Read(p []byte) (int, error) {
+	n, _, err := pc.ReadMessage(p)
+	return n, err
+}
+
+func (pc *pipeConn) ReadMessage(p []byte) (int, bool, error) {
+	msg, err := pc.receive()
+	if err != nil {
+		return 0, false, err
+	}
+
+	n := copy(p, msg)
+
+	return n, n < len(msg), nil
+}
+
+func (pc *pipeConn) receive() ([]byte, error) {
+	select {
+	case <-pc.done:
+		return nil, pc.opError("read", net.ErrClosed)
+	default:
+	}
+
+	// Messages written before the peer closed are read first.
+	select {
+	case msg := <-pc.rx:
+		return msg, nil
+	default:
+	}
+
+	select {
+	case msg := <-pc.rx:
+		return msg, nil
+	case <-pc.peer.done:
+		select {
+		case msg := <-pc.rx:
+			return msg, nil
+		default:
+		}
+
+		return nil, io.EOF
+	case <-pc.done:
+		return nil, pc.opError("read", net.ErrClosed)
+	case <-pc.rdeadline.wait():
+		return nil, pc.opError("read", os.ErrDeadlineExceeded)
+	}
+}
+
+func (pc *pipeConn) Write(p []byte) (int, error) {
+	select {
+	case <-pc.done:
+		return 0, pc.opError("write", net.ErrClosed)
+	case <-pc.peer.done:
+		return 0, pc.opError("write", os.NewSyscallError("write", unix.EPIPE))
+	case <-pc.wdeadline.wait():
+		return 0, pc.opError("write", os.ErrDeadlineExceeded)
+	default:
+	}
+
+	msg := append([]byte(nil), p...)
+
+	select {
+	case pc.tx <- msg:
+		return len(p), nil
+	case <-pc.done:
+		return 0, pc.opError("write", net.ErrClosed)
+	case <-pc.peer.done:
+		return 0, pc.opError("write", os.NewSyscallError("write", unix.EPIPE))
+	case <-pc.wdeadline.wait():
+		return 0, pc.opError("write", os.ErrDeadlineExceeded)
+	}
+}
+
+func (pc *pipeConn) Close() error {
+	pc.closeOnce.Do(func() { close(pc.done) })
+	return nil
+}
+
+func (pc *pipeConn) LocalAddr() net.Addr {
+	return pc.addr
+}
+
+func (pc *pipeConn) RemoteAddr() net.Addr {
+	return pc.peer.addr
+}
+
+func (pc *pipeConn) SetDeadline(t time.Time) error {
+	pc.rdeadline.set(t)
+	pc.wdeadline.set(t)
+	return nil
+}
+
+func (pc *pipeConn) SetReadDeadline(t time.Time) error {
+	pc.rdeadline.set(t)
+	return nil
+}
+
+func (pc *pipeConn) SetWriteDeadline(t time.Time) error {
+	pc.wdeadline.set(t)
+	return nil
+}
+
+// pipeDeadline is a deadline whose channel is closed once it passes.
+type pipeDeadline struct {
+	mu     sync.Mutex
+	timer  *time.Timer
+	cancel chan struct{}
+}
+
+func makePipeDeadline() pipeDeadline {
+	return pipeDeadline{cancel: make(chan struct{})}
+}
+
+func (d *pipeDeadline) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// If the timer has already fired, wait for it to close cancel.
+	if d.timer != nil && !d.timer.Stop() {
+		<-d.cancel
+	}
+
+	d.timer = nil
+
+	expired := false
+	select {
+	case <-d.cancel:
+		expired = true
+	default:
+	}
+
+	if t.IsZero() {
+		if expired {
+			d.cancel = make(chan struct{})
+		}
+
+		return
+	}
+
+	if dur := time.Until(t); dur > 0 {
+		if expired {
+			d.cancel = make(chan struct{})
+		}
+
+		cancel := d.cancel
+		d.timer = time.AfterFunc(dur, func() { close(cancel) })
+
+		return
+	}
+
+	if !expired {
+		close(d.cancel)
+	}
+}
+
+func (d *pipeDeadline) wait() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.cancel
+}
@@ -0,0 +1,356 @@
+package tipc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// ConnState is the state of a ReconnectingConn.
+type ConnState int
+
+const (
+	// StateConnected means the connection is established.
+	StateConnected ConnState = iota
+
+	// StateReconnecting means the connection failed and is being redialed.
+	StateReconnecting
+
+	// StateClosed means Close was called.
+	StateClosed
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateReconnecting:
+		return "reconnecting"
+	case StateClosed:
+		return "closed"
+	}
+
+	return fmt.Sprintf("ConnState(%d)", int(s))
+}
+
+// ErrReconnecting is returned by ReconnectingConn.Write while the connection
+// is being redialed, if ReconnectOptions.FailWrites is set.
+var ErrReconnecting = errors.New("tipc: connection is reconnecting")
+
+// ReconnectOptions configures a ReconnectingConn.
+type ReconnectOptions struct {
+	// MinBackoff and MaxBackoff bound the delay between redial attempts,
+	// which doubles after each failure. They default to 10ms and 5s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// FailWrites makes Write fail with ErrReconnecting while the
+	// connection is down, instead of waiting for it to be redialed.
+	FailWrites bool
+
+	// StateChange, if not nil, is called on every state change. It is
+	// called with the connection's lock held, and must not call methods of
+	// the ReconnectingConn.
+	StateChange func(ConnState)
+}
+
+// ReconnectingConn is a stream connection to a service that is redialed
+// with exponential backoff when it fails, for instance because the node
+// serving it went down. Reads and writes that fail are retried on the new
+// connection; data in flight when the connection failed may be lost, so it
+// is only suitable for protocols that tolerate that.
+type ReconnectingConn struct {
+	addr *unix.SockaddrTIPC
+	opts ReconnectOptions
+	done chan struct{}
+
+	mu        sync.Mutex
+	conn      *Conn
+	ready     chan struct{}
+	closed    bool
+	rdeadline time.Time
+	wdeadline time.Time
+}
+
+// DialReconnecting connects to the service s with a stream socket, and
+// returns a ReconnectingConn that redials s whenever the connection fails.
+// The initial dial is not retried.
+func DialReconnecting(s *unix.SockaddrTIPC, opts ReconnectOptions) (*ReconnectingConn, error) {
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 10 * time.Millisecond
+	}
+
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = 5 * time.Second
+	}
+
+	c, err := DialStream(s)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &ReconnectingConn{
+		addr:  s,
+		opts:  opts,
+		done:  make(chan struct{}),
+		conn:  c,
+		ready: make(chan struct{}),
+	}
+
+	close(r.ready)
+
+	return r, nil
+}
+
+func (r *ReconnectingConn) setState(s ConnState) {
+	if r.opts.StateChange != nil {
+		r.opts.StateChange(s)
+	}
+}
+
+// current returns the established connection, waiting for it to be
+// redialed if necessary.
+func (r *ReconnectingConn) current(op string) (*Conn, error) {
+	for {
+		r.mu.Lock()
+		c, ready, closed := r.conn, r.ready, r.closed
+
+		deadline := r.rdeadline
+		if op == "write" {
+			deadline = r.wdeadline
+		}
+		r.mu.Unlock()
+
+		switch {
+		case closed:
+			return nil, r.opError(op, os.ErrClosed)
+		case c != nil:
+			return c, nil
+		case op == "write" && r.opts.FailWrites:
+			return nil, r.opError(op, ErrReconnecting)
+		}
+
+		var (
+			t       *time.Timer
+			timeout <-chan time.Time
+		)
+
+		if !deadline.IsZero() {
+			d := time.Until(deadline)
+			if d <= 0 {
+				return nil, r.opError(op, os.ErrDeadlineExceeded)
+			}
+
+			t = time.NewTimer(d)
+			timeout = t.C
+		}
+
+		select {
+		case <-ready:
+		case <-r.done:
+		case <-timeout:
+			return nil, r.opError(op, os.ErrDeadlineExceeded)
+		}
+
+		if t != nil {
+			t.Stop()
+		}
+	}
+}
+
+// failed replaces c, which returned err, with a new connection, unless err
+// is a timeout or c was already replaced.
+func (r *ReconnectingConn) failed(c *Conn, err error) bool {
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return false
+	}
+
+	if r.conn != c {
+		return true
+	}
+
+	r.conn = nil
+	r.ready = make(chan struct{})
+	c.Close()
+
+	r.setState(StateReconnecting)
+
+	go r.redial(r.ready)
+
+	return true
+}
+
+func (r *ReconnectingConn) redial(ready chan struct{}) {
+	backoff := r.opts.MinBackoff
+
+	for {
+		c, err := DialStream(r.addr)
+		if err == nil {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+
+			if r.closed {
+				c.Close()
+				return
+			}
+
+			c.SetReadDeadline(r.rdeadline)
+			c.SetWriteDeadline(r.wdeadline)
+
+			r.conn = c
+			close(ready)
+
+			r.setState(StateConnected)
+
+			return
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-r.done:
+			return
+		}
+
+		if backoff *= 2; backoff > r.opts.MaxBackoff {
+			backoff = r.opts.MaxBackoff
+		}
+	}
+}
+
+// Read reads from the connection, redialing and retrying if it fails.
+func (r *ReconnectingConn) Read(p []byte) (int, error) {
+	for {
+		c, err := r.current("read")
+		if err != nil {
+			return 0, err
+		}
+
+		n, err := c.Read(p)
+		if err == nil || !r.failed(c, err) {
+			return n, err
+		}
+	}
+}
+
+// Write writes to the connection, redialing and writing the rest of p to the
+// new connection if it fails.
+func (r *ReconnectingConn) Write(p []byte) (int, error) {
+	var written int
+
+	for {
+		c, err := r.current("write")
+		if err != nil {
+			return written, err
+		}
+
+		n, err := c.Write(p[written:])
+		written += n
+
+		if err == nil || !r.failed(c, err) {
+			return written, err
+		}
+	}
+}
+
+// Close closes the connection and stops redialing.
+func (r *ReconnectingConn) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+
+	r.closed = true
+	close(r.done)
+
+	var err error
+	if r.conn != nil {
+		err = r.conn.Close()
+	}
+
+	r.setState(StateClosed)
+
+	return err
+}
+
+// LocalAddr returns the local address of the current connection, or nil
+// while reconnecting.
+func (r *ReconnectingConn) LocalAddr() net.Addr {
+	if c := r.connected(); c != nil {
+		return c.LocalAddr()
+	}
+
+	return nil
+}
+
+// RemoteAddr returns the remote address of the current connection, or nil
+// while reconnecting.
+func (r *ReconnectingConn) RemoteAddr() net.Addr {
+	if c := r.connected(); c != nil {
+		return c.RemoteAddr()
+	}
+
+	return nil
+}
+
+func (r *ReconnectingConn) connected() *Conn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.conn
+}
+
+// SetDeadline sets the read and write deadlines. The deadlines also bound
+// the time spent waiting for the connection to be redialed, and carry over
+// to new connections.
+func (r *ReconnectingConn) SetDeadline(t time.Time) error {
+	if err := r.SetReadDeadline(t); err != nil {
+		return err
+	}
+
+	return r.SetWriteDeadline(t)
+}
+
+func (r *ReconnectingConn) SetReadDeadline(t time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rdeadline = t
+
+	if r.conn != nil {
+		return r.conn.SetReadDeadline(t)
+	}
+
+	return nil
+}
+
+func (r *ReconnectingConn) SetWriteDeadline(t time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.wdeadline = t
+
+	if r.conn != nil {
+		return r.conn.SetWriteDeadline(t)
+	}
+
+	return nil
+}
+
+func (r *ReconnectingConn) opError(op string, err error) error {
+	return &net.OpError{Op: op, Net: "tipc-stream", Addr: &Addr{Sockaddr: r.addr}, Err: err}
+}
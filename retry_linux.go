@@ -0,0 +1,74 @@
+package tipc
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// RetryPolicy configures WriteToRetry.
+type RetryPolicy struct {
+	// MaxAttempts is the number of sends attempted, including the first.
+	// It defaults to 3.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, which doubles after
+	// each further failure up to MaxBackoff. They default to 10ms and 1s.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// retryWrite performs a single send for WriteToRetry. It is replaced in
+// tests to simulate failures.
+var retryWrite = (*Conn).WriteTo
+
+// WriteToRetry is like WriteTo, but retries the send according to policy
+// while it fails with a transient error: the kernel running short of memory
+// or buffers for the message (ENOMEM, ENOBUFS), or the send not being able
+// to proceed (EAGAIN). It returns the error of the last attempt once the
+// attempts are exhausted, and any other error at once.
+//
+// Link congestion does not need retrying, as WriteTo waits for it to clear,
+// up to the write deadline. A destination that is overloaded rejects the
+// message after it was sent, which is reported by ReadFrom as a
+// *RejectedError with code RejectOverload rather than by the send. The
+// write deadline bounds each attempt, but not the delays between them.
+func (tc *Conn) WriteToRetry(p []byte, addr *Addr, policy RetryPolicy) (int, error) {
+	attempts := policy.MaxAttempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+
+	backoff := policy.Backoff
+	if backoff <= 0 {
+		backoff = 10 * time.Millisecond
+	}
+
+	maxBackoff := policy.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = time.Second
+	}
+
+	if addr == nil {
+		return 0, tc.opError("write", errMissingAddress)
+	}
+
+	for i := 1; ; i++ {
+		n, err := retryWrite(tc, p, addr)
+		if err == nil || i >= attempts || !isTransient(err) {
+			return n, err
+		}
+
+		time.Sleep(backoff)
+
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// isTransient reports whether a failed send may succeed if retried.
+func isTransient(err error) bool {
+	return errors.Is(err, unix.ENOBUFS) || errors.Is(err, unix.ENOMEM) || errors.Is(err, unix.EAGAIN)
+}
@@ -0,0 +1,98 @@
+package tipc
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrServerClosed is returned by Server.Serve after a call to Shutdown.
+var ErrServerClosed = errors.New("tipc: Server closed")
+
+// Server accepts connections on a service range and passes each to Handler
+// in its own goroutine.
+type Server struct {
+	// ServiceRange is the service range to bind.
+	ServiceRange *unix.TIPCServiceRange
+
+	// Scope is the scope of the binding. If zero, TIPC_CLUSTER_SCOPE is
+	// used.
+	Scope int
+
+	// Handler is called with each accepted connection. The connection is
+	// closed when Handler returns.
+	Handler func(*Conn)
+
+	mu       sync.Mutex
+	listener *Listener
+	closed   bool
+}
+
+// Serve listens on the server's service range and handles incoming
+// connections until Shutdown is called, after which it returns
+// ErrServerClosed.
+func (s *Server) Serve() error {
+	scope := s.Scope
+	if scope == 0 {
+		scope = unix.TIPC_CLUSTER_SCOPE
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+
+	l, err := Listen(scope, s.ServiceRange)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		c, err := l.AcceptTIPC()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+
+			l.Close()
+			return err
+		}
+
+		go func() {
+			defer c.Close()
+
+			s.Handler(c)
+		}()
+	}
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.closed
+}
+
+// Shutdown stops the server from accepting connections and, like
+// Listener.CloseGraceful, waits for the accepted connections to be closed,
+// as they are when their handlers return. If ctx expires first, Shutdown
+// closes the remaining connections and returns the context's error.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	l := s.listener
+	s.mu.Unlock()
+
+	if l == nil {
+		return nil
+	}
+
+	return l.CloseGraceful(ctx)
+}
@@ -0,0 +1,165 @@
+package tipc
+
+import (
+	"errors"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// siocGetNodeID is SIOCGETNODEID from linux/tipc.h, which x/sys/unix does
+// not define.
+const siocGetNodeID = unix.SIOCPROTOPRIVATE + 1
+
+// ErrNotAvailable is returned when the kernel does not provide the requested
+// information.
+var ErrNotAvailable = errors.New("tipc: information not available")
+
+// parseDestName returns the service name carried in a TIPC_DESTNAME control
+// message in oob, or nil if there is none.
+func parseDestName(oob []byte) *unix.TIPCServiceName {
+	if len(oob) == 0 {
+		return nil
+	}
+
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil
+	}
+
+	for _, m := range msgs {
+		if m.Header.Level != unix.SOL_TIPC || m.Header.Type != unix.TIPC_DESTNAME {
+			continue
+		}
+
+		if len(m.Data) < 12 {
+			continue
+		}
+
+		return &unix.TIPCServiceName{
+			Type:     nativeEndian.Uint32(m.Data[0:]),
+			Instance: nativeEndian.Uint32(m.Data[4:]),
+		}
+	}
+
+	return nil
+}
+
+// recordService remembers the service name a connection was made to, if oob
+// carries one and none is known yet.
+func (tc *Conn) recordService(oob []byte) {
+	name := parseDestName(oob)
+	if name == nil {
+		return
+	}
+
+	tc.addrmu.Lock()
+	if tc.service == nil {
+		tc.service = name
+	}
+	tc.addrmu.Unlock()
+}
+
+// ConnectedService returns the service name the connection was made to.
+//
+// TIPC reports the name with each data message received on a connection
+// made to a service name, on both the accepting and the connecting side, and
+// ConnectedService remembers it from the first message read. Until a message
+// has been read, it peeks at the first queued message without consuming it.
+// ErrNotAvailable is returned if no data message has arrived yet, or if the
+// connection was made to a port identity rather than a service name.
+func (tc *Conn) ConnectedService() (*unix.TIPCServiceName, error) {
+	tc.addrmu.Lock()
+	name := tc.service
+	tc.addrmu.Unlock()
+
+	if name != nil {
+		n := *name
+		return &n, nil
+	}
+
+	var (
+		buf   [1]byte
+		oob   = make([]byte, rejectOOBSize)
+		oobn  int
+		rerr  error
+		flags = unix.MSG_PEEK | unix.MSG_DONTWAIT
+	)
+
+	cerr := tc.sc.Control(func(fd uintptr) {
+		rerr = ignoringEINTR(func() (err error) {
+			_, oobn, _, _, err = unix.Recvmsg(int(fd), buf[:], oob, flags)
+			return
+		})
+	})
+
+	if cerr != nil {
+		return nil, tc.opError("read", cerr)
+	}
+
+	if rerr != nil {
+		return nil, ErrNotAvailable
+	}
+
+	tc.recordService(oob[:oobn])
+
+	tc.addrmu.Lock()
+	name = tc.service
+	tc.addrmu.Unlock()
+
+	if name == nil {
+		return nil, ErrNotAvailable
+	}
+
+	n := *name
+
+	return &n, nil
+}
+
+// PeerNodeID returns the 128-bit identity of the node the peer socket is on.
+// Unlike the 32-bit address in RemoteAddr, which a node may derive anew from
+// its identity, the identity stays the same across restarts of the peer node.
+//
+// The identity is looked up with the SIOCGETNODEID ioctl using the peer's
+// node address. ErrNotAvailable is returned if the kernel predates node
+// identities or does not know the peer node.
+func (tc *Conn) PeerNodeID() (NodeID, error) {
+	var id NodeID
+
+	ra, ok := tc.RemoteAddr().(*Addr)
+	if !ok {
+		return id, tc.opError("ioctl", unix.ENOTCONN)
+	}
+
+	node, ok := ra.Node()
+	if !ok {
+		return id, ErrNotAvailable
+	}
+
+	req := unix.TIPCSIOCNodeIDReq{Peer: node}
+
+	var errno unix.Errno
+
+	cerr := tc.sc.Control(func(fd uintptr) {
+		_, _, errno = unix.Syscall(unix.SYS_IOCTL, fd, siocGetNodeID, uintptr(unsafe.Pointer(&req)))
+	})
+
+	if cerr != nil {
+		return id, tc.opError("ioctl", cerr)
+	}
+
+	switch errno {
+	case 0:
+	case unix.ENOTTY, unix.EINVAL, unix.EOPNOTSUPP, unix.EADDRNOTAVAIL:
+		return id, ErrNotAvailable
+	default:
+		return id, tc.opError("ioctl", os.NewSyscallError("ioctl", errno))
+	}
+
+	for i, b := range req.Id {
+		id[i] = byte(b)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,237 @@
+package tipc
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func (tc *Conn) opError(op string, err error) error {
+	return &net.OpError{
+		Op:     op,
+		Net:    opNetwork(tc.typ),
+		Source: tc.LocalAddr(),
+		Addr:   tc.RemoteAddr(),
+		Err:    err,
+	}
+}
+
+func (tc *Conn) setsockoptInt(level, opt, value int) error {
+	var serr error
+
+	cerr := tc.sc.Control(func(fd uintptr) {
+		serr = unix.SetsockoptInt(int(fd), level, opt, value)
+	})
+
+	if cerr != nil {
+		return tc.opError("setsockopt", cerr)
+	}
+
+	if serr != nil {
+		return tc.opError("setsockopt", os.NewSyscallError("setsockopt", serr))
+	}
+
+	return nil
+}
+
+// setsockoptNoValue sets an option that takes no value. TIPC rejects such
+// options with EINVAL unless optval is NULL and optlen zero, which
+// unix.SetsockoptInt cannot pass.
+func (tc *Conn) setsockoptNoValue(level, opt int) error {
+	var errno unix.Errno
+
+	cerr := tc.sc.Control(func(fd uintptr) {
+		_, _, errno = unix.Syscall6(unix.SYS_SETSOCKOPT, fd, uintptr(level), uintptr(opt), 0, 0, 0)
+	})
+
+	if cerr != nil {
+		return tc.opError("setsockopt", cerr)
+	}
+
+	if errno != 0 {
+		return tc.opError("setsockopt", os.NewSyscallError("setsockopt", errno))
+	}
+
+	return nil
+}
+
+func (tc *Conn) getsockoptInt(level, opt int) (int, error) {
+	var (
+		value int
+		gerr  error
+	)
+
+	cerr := tc.sc.Control(func(fd uintptr) {
+		value, gerr = unix.GetsockoptInt(int(fd), level, opt)
+	})
+
+	if cerr != nil {
+		return 0, tc.opError("getsockopt", cerr)
+	}
+
+	if gerr != nil {
+		return 0, tc.opError("getsockopt", os.NewSyscallError("getsockopt", gerr))
+	}
+
+	return value, nil
+}
+
+// SockRecvQDepth returns the number of messages in the socket's receive queue.
+// The kernel only reports the receive queue depths; they cannot be set.
+func (tc *Conn) SockRecvQDepth() (int, error) {
+	return tc.getsockoptInt(unix.SOL_TIPC, unix.TIPC_SOCK_RECVQ_DEPTH)
+}
+
+// NodeRecvQDepth returns the TIPC_NODE_RECVQ_DEPTH option. The node queue
+// limit is obsolete, and recent kernels always report 0.
+func (tc *Conn) NodeRecvQDepth() (int, error) {
+	return tc.getsockoptInt(unix.SOL_TIPC, unix.TIPC_NODE_RECVQ_DEPTH)
+}
+
+// RecvQUsed returns the number of bytes currently queued for reading on the
+// socket.
+func (tc *Conn) RecvQUsed() (int, error) {
+	return tc.getsockoptInt(unix.SOL_TIPC, unix.TIPC_SOCK_RECVQ_USED)
+}
+
+// Multicast delivery methods for SetMulticastMethod.
+const (
+	// MulticastBroadcast sends multicast messages using L2 broadcast.
+	MulticastBroadcast = unix.TIPC_MCAST_BROADCAST
+
+	// MulticastReplicast sends multicast messages as replicated unicast,
+	// one copy per destination node.
+	MulticastReplicast = unix.TIPC_MCAST_REPLICAST
+)
+
+// SetMulticastMethod forces the delivery method used for multicast messages
+// sent on the socket, either MulticastBroadcast or MulticastReplicast.
+//
+// TIPC offers no way to choose the bearer a socket or message is sent on.
+// Replicast copies follow the unicast links to each node, which use the
+// bearers of highest link priority, while broadcast uses every bearer. The
+// bearers themselves are configured with netlink.BearerSet and
+// netlink.BearerDisable.
+func (tc *Conn) SetMulticastMethod(method int) error {
+	switch method {
+	case MulticastBroadcast, MulticastReplicast:
+	default:
+		return tc.opError("setsockopt", fmt.Errorf("unknown multicast method %d", method))
+	}
+
+	return tc.setsockoptNoValue(unix.SOL_TIPC, method)
+}
+
+var errConnectionOriented = errors.New("option only applies to connectionless sockets")
+
+func (tc *Conn) setConnectionlessBool(opt int, on bool) error {
+	typ, err := tc.getsockoptInt(unix.SOL_SOCKET, unix.SO_TYPE)
+	if err != nil {
+		return err
+	}
+
+	if typ != unix.SOCK_RDM && typ != unix.SOCK_DGRAM {
+		return tc.opError("setsockopt", errConnectionOriented)
+	}
+
+	value := 0
+	if on {
+		value = 1
+	}
+
+	return tc.setsockoptInt(unix.SOL_TIPC, opt, value)
+}
+
+// SetImportance sets the importance of the messages sent on the socket, from
+// unix.TIPC_LOW_IMPORTANCE, the default, to unix.TIPC_CRITICAL_IMPORTANCE.
+// Under congestion, links hold back or drop less important messages first.
+//
+// Importance is a property of the socket: TIPC ignores control messages on
+// send, so it cannot be chosen per message. Messages of different importance
+// are sent on separate sockets.
+func (tc *Conn) SetImportance(importance int) error {
+	if !validImportance(importance) {
+		return tc.opError("setsockopt", errInvalidImportance(importance))
+	}
+
+	return tc.setsockoptInt(unix.SOL_TIPC, unix.TIPC_IMPORTANCE, importance)
+}
+
+// Importance returns the importance of the messages sent on the socket.
+func (tc *Conn) Importance() (int, error) {
+	return tc.getsockoptInt(unix.SOL_TIPC, unix.TIPC_IMPORTANCE)
+}
+
+// SetSrcDroppable controls whether messages sent on the socket may be
+// dropped, rather than queued, when the link is congested. It is only
+// meaningful on SOCK_RDM and SOCK_DGRAM sockets; stream and seqpacket sockets
+// ignore it, and an error is returned for them.
+func (tc *Conn) SetSrcDroppable(on bool) error {
+	return tc.setConnectionlessBool(unix.TIPC_SRC_DROPPABLE, on)
+}
+
+// SrcDroppable reports whether the TIPC_SRC_DROPPABLE option is set.
+func (tc *Conn) SrcDroppable() (bool, error) {
+	v, err := tc.getsockoptInt(unix.SOL_TIPC, unix.TIPC_SRC_DROPPABLE)
+	return v != 0, err
+}
+
+// SetDestDroppable controls whether messages sent on the socket are dropped,
+// rather than returned to the sender, when they cannot be delivered. It is
+// only meaningful on SOCK_RDM and SOCK_DGRAM sockets; stream and seqpacket
+// sockets ignore it, and an error is returned for them.
+func (tc *Conn) SetDestDroppable(on bool) error {
+	return tc.setConnectionlessBool(unix.TIPC_DEST_DROPPABLE, on)
+}
+
+// DestDroppable reports whether the TIPC_DEST_DROPPABLE option is set.
+func (tc *Conn) DestDroppable() (bool, error) {
+	v, err := tc.getsockoptInt(unix.SOL_TIPC, unix.TIPC_DEST_DROPPABLE)
+	return v != 0, err
+}
+
+// SetReadBuffer sets the size of the operating system's receive buffer
+// associated with the connection.
+func (tc *Conn) SetReadBuffer(bytes int) error {
+	return tc.setsockoptInt(unix.SOL_SOCKET, unix.SO_RCVBUF, bytes)
+}
+
+// SetWriteBuffer sets the size of the operating system's transmit buffer
+// associated with the connection.
+func (tc *Conn) SetWriteBuffer(bytes int) error {
+	return tc.setsockoptInt(unix.SOL_SOCKET, unix.SO_SNDBUF, bytes)
+}
+
+// SetLinger sets the SO_LINGER option on the socket. A negative sec disables
+// lingering, zero requests an abortive close, and a positive value lingers
+// for up to sec seconds.
+//
+// The option is stored by the socket layer, but TIPC's close does not
+// currently wait for unsent data; messages already queued are delivered
+// regardless.
+func (tc *Conn) SetLinger(sec int) error {
+	var l unix.Linger
+	if sec >= 0 {
+		l.Onoff = 1
+		l.Linger = int32(sec)
+	}
+
+	var serr error
+
+	cerr := tc.sc.Control(func(fd uintptr) {
+		serr = unix.SetsockoptLinger(int(fd), unix.SOL_SOCKET, unix.SO_LINGER, &l)
+	})
+
+	if cerr != nil {
+		return tc.opError("setsockopt", cerr)
+	}
+
+	if serr != nil {
+		return tc.opError("setsockopt", os.NewSyscallError("setsockopt", serr))
+	}
+
+	return nil
+}
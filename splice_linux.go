@@ -0,0 +1,122 @@
+package tipc
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxSpliceSize is the number of bytes moved through the pipe at a time,
+// matching the default pipe capacity.
+const maxSpliceSize = 64 << 10
+
+// SpliceFrom copies data from r to the connection until EOF or an error,
+// returning the number of bytes written. When r is a *Conn or a
+// *net.TCPConn, the data is moved with splice(2) through a pipe without
+// being copied to user space; otherwise, or if the kernel cannot splice from
+// r, it falls back to io.Copy.
+//
+// Conn cannot implement io.ReaderFrom itself, since its ReadFrom method is
+// the net.PacketConn one. SpliceFrom is intended for proxying byte streams;
+// on a seqpacket connection the message boundaries of the source are not
+// preserved.
+func (tc *Conn) SpliceFrom(r io.Reader) (int64, error) {
+	if tc.typ == unix.SOCK_STREAM || tc.typ == unix.SOCK_SEQPACKET {
+		if src, ok := spliceSource(r); ok {
+			n, handled, err := tc.splice(src)
+			if handled {
+				return n, err
+			}
+		}
+	}
+
+	return io.Copy(tc, r)
+}
+
+func spliceSource(r io.Reader) (syscall.RawConn, bool) {
+	switch src := r.(type) {
+	case *Conn:
+		return src.sc, true
+	case *net.TCPConn:
+		sc, err := src.SyscallConn()
+		return sc, err == nil
+	}
+
+	return nil, false
+}
+
+// splice moves data from src to the connection through a pipe. handled is
+// false if nothing was moved because the kernel cannot splice from src.
+func (tc *Conn) splice(src syscall.RawConn) (written int64, handled bool, err error) {
+	var p [2]int
+	if err := unix.Pipe2(p[:], unix.O_CLOEXEC|unix.O_NONBLOCK); err != nil {
+		return 0, false, nil
+	}
+
+	defer unix.Close(p[0])
+	defer unix.Close(p[1])
+
+	const flags = unix.SPLICE_F_MOVE | unix.SPLICE_F_NONBLOCK
+
+	for {
+		var (
+			n    int
+			serr error
+		)
+
+		rerr := src.Read(func(fd uintptr) bool {
+			var n64 int64
+			n64, serr = unix.Splice(int(fd), nil, p[1], nil, maxSpliceSize, flags)
+			n = int(n64)
+			return !errors.Is(serr, unix.EAGAIN)
+		})
+
+		if rerr != nil {
+			return written, true, tc.opError("readfrom", rerr)
+		}
+
+		if serr != nil {
+			if written == 0 && (serr == unix.EINVAL || serr == unix.ENOSYS) {
+				return 0, false, nil
+			}
+
+			// A TIPC source reports the peer closing like Read does.
+			if serr == unix.ECONNRESET {
+				return written, true, nil
+			}
+
+			return written, true, tc.opError("readfrom", os.NewSyscallError("splice", serr))
+		}
+
+		if n == 0 {
+			return written, true, nil
+		}
+
+		for n > 0 {
+			var m int
+
+			werr := tc.sc.Write(func(fd uintptr) bool {
+				var m64 int64
+				m64, serr = unix.Splice(p[0], nil, int(fd), nil, n, flags)
+				m = int(m64)
+				return !errors.Is(serr, unix.EAGAIN)
+			})
+
+			if werr != nil {
+				return written, true, tc.opError("readfrom", werr)
+			}
+
+			if serr != nil {
+				return written, true, tc.opError("readfrom", os.NewSyscallError("splice", serr))
+			}
+
+			n -= m
+			written += int64(m)
+			tc.countWrite(m)
+		}
+	}
+}
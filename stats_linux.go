@@ -0,0 +1,48 @@
+package tipc
+
+import "sync/atomic"
+
+// ConnStats holds the traffic counters of a connection.
+type ConnStats struct {
+	// BytesRead and BytesWritten are the payload bytes read from and
+	// written to the socket.
+	BytesRead    int64
+	BytesWritten int64
+
+	// Reads and Writes are the system calls that completed a read or a
+	// write on the socket. Calls that would have blocked, and were retried
+	// once the socket became ready, are not counted, nor are reads with
+	// Peek, which leave the data queued.
+	Reads  int64
+	Writes int64
+}
+
+// Stats returns the traffic counters of the connection, counted over its
+// lifetime. It is safe to call concurrently with reads and writes, though
+// the counters may then not reflect the same instant.
+func (tc *Conn) Stats() ConnStats {
+	return ConnStats{
+		BytesRead:    atomic.LoadInt64(&tc.rbytes),
+		BytesWritten: atomic.LoadInt64(&tc.wbytes),
+		Reads:        atomic.LoadInt64(&tc.rcalls),
+		Writes:       atomic.LoadInt64(&tc.wcalls),
+	}
+}
+
+// countRead records a read system call that returned n bytes.
+func (tc *Conn) countRead(n int) {
+	atomic.AddInt64(&tc.rcalls, 1)
+
+	if n > 0 {
+		atomic.AddInt64(&tc.rbytes, int64(n))
+	}
+}
+
+// countWrite records a write system call that wrote n bytes.
+func (tc *Conn) countWrite(n int) {
+	atomic.AddInt64(&tc.wcalls, 1)
+
+	if n > 0 {
+		atomic.AddInt64(&tc.wbytes, int64(n))
+	}
+}
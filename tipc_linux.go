@@ -1,77 +1,323 @@
 package tipc
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"reflect"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unsafe"
 
 	"golang.org/x/sys/unix"
 )
 
 type Addr struct {
 	unix.Sockaddr
+
+	// Net is the network of the socket the address was obtained from, such
+	// as "tipc-stream" or "tipc-rdm". It is empty for addresses constructed
+	// by the caller or by ParseAddr.
+	Net string
 }
 
+// Network returns a.Net, or "tipc" if it is empty. Use IsNetwork to check
+// for any TIPC network name.
 func (a *Addr) Network() string {
+	if a.Net != "" {
+		return a.Net
+	}
+
 	return "tipc"
 }
 
+// IsNetwork reports whether network is one of the network names used by
+// this package: "tipc", or "tipc-" followed by a socket type.
+func IsNetwork(network string) bool {
+	switch network {
+	case "tipc", "tipc-stream", "tipc-seqpacket", "tipc-dgram", "tipc-rdm":
+		return true
+	}
+
+	return false
+}
+
+// socketNetwork returns the network name for sockets of type typ.
+func socketNetwork(typ int) string {
+	switch typ {
+	case unix.SOCK_STREAM:
+		return "tipc-stream"
+	case unix.SOCK_SEQPACKET:
+		return "tipc-seqpacket"
+	case unix.SOCK_DGRAM:
+		return "tipc-dgram"
+	case unix.SOCK_RDM:
+		return "tipc-rdm"
+	}
+
+	return ""
+}
+
+// String formats the address in the form accepted by ParseAddr. Node
+// addresses are always formatted as legacy hex addresses; see NodeID for the
+// node identity form.
 func (a *Addr) String() string {
-	ta := a.Sockaddr.(*unix.SockaddrTIPC)
+	ta, ok := a.Sockaddr.(*unix.SockaddrTIPC)
+	if !ok {
+		return fmt.Sprintf("%T %+v", a.Sockaddr, a.Sockaddr)
+	}
+
+	var s string
+
 	switch sa := ta.Addr.(type) {
-	default:
-		panic("unknown addr")
 	case *unix.TIPCSocketAddr:
-		return fmt.Sprintf("port=%d,node=%x", sa.Ref, sa.Node)
+		s = fmt.Sprintf("port=%d,node=%x", sa.Ref, sa.Node)
+	case *unix.TIPCServiceName:
+		s = fmt.Sprintf("type=%d,instance=%d,domain=%x", sa.Type, sa.Instance, sa.Domain)
+	case *unix.TIPCServiceRange:
+		s = fmt.Sprintf("type=%d,lower=%d,upper=%d", sa.Type, sa.Lower, sa.Upper)
+	default:
+		return fmt.Sprintf("%T %+v", ta.Addr, ta.Addr)
 	}
 
-	_ = ta
-	return fmt.Sprintf("%T %+v", ta.Addr, ta.Addr)
+	switch ta.Scope {
+	case unix.TIPC_NODE_SCOPE:
+		s += ",scope=node"
+	case unix.TIPC_ZONE_SCOPE:
+		s += ",scope=zone"
+	}
+
+	return s
 }
 
+const defaultBacklog = 32
+
+// Listen binds a stream socket to the service range s within scope and
+// listens for connections on it.
+//
+// Unlike TCP ports, TIPC service ranges may be bound by any number of sockets
+// at once, with incoming connections distributed among them, and a socket's
+// bindings are withdrawn as soon as it is closed. There is therefore no
+// equivalent of SO_REUSEADDR: a restarted server can bind its service range
+// immediately, or even before the old server has closed its listener.
 func Listen(scope int, s *unix.TIPCServiceRange) (*Listener, error) {
-	sock, err := unix.Socket(unix.AF_TIPC, unix.SOCK_STREAM|unix.SOCK_CLOEXEC, 0)
+	return ListenBacklog(scope, s, defaultBacklog)
+}
+
+// ListenSequentialPacket is like Listen, but listens on a SOCK_SEQPACKET
+// socket, for use with DialSequentialPacket.
+func ListenSequentialPacket(scope int, s *unix.TIPCServiceRange) (*Listener, error) {
+	return (&ListenConfig{}).ListenSequentialPacket(scope, s)
+}
+
+// ListenBacklog is like Listen, but allows the length of the pending
+// connection queue to be specified.
+func ListenBacklog(scope int, s *unix.TIPCServiceRange, backlog int) (*Listener, error) {
+	if backlog <= 0 {
+		return nil, fmt.Errorf("tipc: invalid listen backlog %d", backlog)
+	}
+
+	lc := &ListenConfig{Backlog: backlog}
+
+	return lc.Listen(scope, s)
+}
+
+// ListenConfig contains options for listening on a service range.
+type ListenConfig struct {
+	// Backlog is the length of the pending connection queue. If zero, a
+	// default of 32 is used.
+	Backlog int
+
+	// If Control is not nil, it is called after creating the socket but
+	// before binding it. network is "tipc-stream" or "tipc-seqpacket", and
+	// address is the service range being bound.
+	Control func(network, address string, c syscall.RawConn) error
+}
+
+// Listen binds a stream socket to the service range s within scope and
+// listens for connections on it.
+func (lc *ListenConfig) Listen(scope int, s *unix.TIPCServiceRange) (*Listener, error) {
+	return lc.listen(unix.SOCK_STREAM, scope, s)
+}
+
+// ListenSequentialPacket is like Listen, but listens on a SOCK_SEQPACKET
+// socket.
+func (lc *ListenConfig) ListenSequentialPacket(scope int, s *unix.TIPCServiceRange) (*Listener, error) {
+	return lc.listen(unix.SOCK_SEQPACKET, scope, s)
+}
+
+func (lc *ListenConfig) listen(typ, scope int, s *unix.TIPCServiceRange) (*Listener, error) {
+	backlog := lc.Backlog
+	if backlog == 0 {
+		backlog = defaultBacklog
+	}
+
+	if backlog < 0 {
+		return nil, fmt.Errorf("tipc: invalid listen backlog %d", backlog)
+	}
+
+	sock, err := socket(typ)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := unix.SetNonblock(sock, true); err != nil {
+		unix.Close(sock)
+		return nil, err
+	}
+
+	conn, err := newConn(sock)
+	if err != nil {
+		return nil, err
+	}
+
+	if lc.Control != nil {
+		address := fmt.Sprintf("{%d,%d,%d}", s.Type, s.Lower, s.Upper)
+		network := "tipc-stream"
+		if typ == unix.SOCK_SEQPACKET {
+			network = "tipc-seqpacket"
+		}
+
+		if err := lc.Control(network, address, conn.sc); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
 	sa := &unix.SockaddrTIPC{
 		Scope: scope,
 		Addr:  s,
 	}
 
-	if err := unix.Bind(sock, sa); err != nil {
-		unix.Close(sock)
+	if err := conn.bind(sa); err != nil {
+		conn.Close()
 		return nil, err
 	}
 
-	if err := unix.Listen(sock, 32); err != nil {
-		unix.Close(sock)
-		return nil, err
+	var lerr error
+
+	cerr := conn.sc.Control(func(fd uintptr) {
+		lerr = unix.Listen(int(fd), backlog)
+	})
+
+	if cerr == nil {
+		cerr = lerr
 	}
 
-	if err := unix.SetNonblock(sock, true); err != nil {
-		return nil, err
+	if cerr != nil {
+		conn.Close()
+		return nil, cerr
 	}
 
-	conn, err := newConn(sock)
+	sr := *s
+
+	l := &Listener{conn: conn, scope: scope, srange: &sr}
+	l.addBinding(scope, s)
+
+	return l, nil
+}
+
+// ErrTIPCUnavailable is returned when a TIPC socket cannot be created because
+// the kernel does not support TIPC, usually because the tipc module is not
+// loaded. The returned error also wraps the underlying errno.
+var ErrTIPCUnavailable = errors.New("tipc: TIPC not available")
+
+type unavailableError struct {
+	err error
+}
+
+func (e *unavailableError) Error() string {
+	return ErrTIPCUnavailable.Error() + ": " + e.err.Error()
+}
+
+func (e *unavailableError) Is(target error) bool {
+	return target == ErrTIPCUnavailable
+}
+
+func (e *unavailableError) Unwrap() error {
+	return e.err
+}
+
+// socket creates a close-on-exec TIPC socket of type typ.
+func socket(typ int) (int, error) {
+	fd, err := unix.Socket(unix.AF_TIPC, typ|unix.SOCK_CLOEXEC, 0)
 	if err != nil {
-		return nil, err
+		return -1, socketError(err)
 	}
 
-	return &Listener{conn: conn}, nil
+	return fd, nil
+}
+
+func socketError(err error) error {
+	switch err {
+	case unix.EAFNOSUPPORT, unix.EPROTONOSUPPORT:
+		return &unavailableError{err: err}
+	}
+
+	return err
+}
+
+// ignoringEINTR calls fn until it returns an error other than EINTR. As in
+// the standard library's netFD, an interrupted syscall is retried directly
+// rather than by waiting on the poller, which may not report the socket ready
+// again.
+func ignoringEINTR(fn func() error) error {
+	for {
+		err := fn()
+		if err != unix.EINTR {
+			return err
+		}
+	}
 }
 
 type Listener struct {
 	conn *Conn
+
+	// the service range the listener was created with.
+	scope  int
+	srange *unix.TIPCServiceRange
+
+	// importance+1 to set on accepted connections, or 0 to leave it alone.
+	accimp int32
+
+	bindmu   sync.Mutex
+	bindings []Binding
+
+	// conns holds the accepted connections that are still open, and
+	// closing is set by CloseGraceful to stop tracking new ones.
+	connmu  sync.Mutex
+	conns   map[*Conn]struct{}
+	closing bool
+	connwg  sync.WaitGroup
 }
 
+// Binding is a service range a Listener is bound to.
+type Binding struct {
+	Scope int
+	Range *unix.TIPCServiceRange
+}
+
+// Accept implements the Accept method in the net.Listener interface; it
+// waits for the next connection and returns it as a net.Conn.
 func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.AcceptTIPC()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// AcceptTIPC waits for the next connection and returns it as a *Conn. Once
+// the listener is closed, pending and later calls return an error wrapping
+// net.ErrClosed.
+func (l *Listener) AcceptTIPC() (*Conn, error) {
 	var (
 		newfd int
 		err   error
@@ -79,12 +325,19 @@ func (l *Listener) Accept() (net.Conn, error) {
 	)
 
 	cerr := l.conn.sc.Read(func(fd uintptr) bool {
-		newfd, sa, err = unix.Accept(int(fd))
+		err = ignoringEINTR(func() (err error) {
+			newfd, sa, err = unix.Accept(int(fd))
+			return
+		})
 
 		return !errors.Is(err, unix.EAGAIN)
 	})
 
 	if cerr != nil {
+		if l.conn.isClosed() {
+			return nil, &net.OpError{Op: "accept", Net: l.conn.network(), Addr: l.Addr(), Err: net.ErrClosed}
+		}
+
 		return nil, cerr
 	}
 
@@ -101,28 +354,311 @@ func (l *Listener) Accept() (net.Conn, error) {
 		return nil, err
 	}
 
-	c.remote = &Addr{sa}
+	c.remote = c.newAddr(sa)
+
+	if imp := atomic.LoadInt32(&l.accimp); imp != 0 {
+		if err := c.setsockoptInt(unix.SOL_TIPC, unix.TIPC_IMPORTANCE, int(imp-1)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	if !l.track(c) {
+		c.Close()
+		return nil, &net.OpError{Op: "accept", Net: l.conn.network(), Addr: l.Addr(), Err: net.ErrClosed}
+	}
 
 	return c, nil
 }
 
+// track records c as an open accepted connection until it is closed. It
+// reports false once CloseGraceful has been called.
+func (l *Listener) track(c *Conn) bool {
+	l.connmu.Lock()
+	defer l.connmu.Unlock()
+
+	if l.closing {
+		return false
+	}
+
+	if l.conns == nil {
+		l.conns = make(map[*Conn]struct{})
+	}
+
+	l.conns[c] = struct{}{}
+	l.connwg.Add(1)
+
+	c.onClose = func() {
+		l.connmu.Lock()
+		delete(l.conns, c)
+		l.connmu.Unlock()
+
+		l.connwg.Done()
+	}
+
+	return true
+}
+
+// SetAcceptedImportance sets the TIPC_IMPORTANCE option on every connection
+// accepted from now on, before AcceptTIPC returns it. importance ranges from
+// unix.TIPC_LOW_IMPORTANCE to unix.TIPC_CRITICAL_IMPORTANCE. By default an
+// accepted connection takes the importance of the client's connection
+// request.
+func (l *Listener) SetAcceptedImportance(importance int) error {
+	if !validImportance(importance) {
+		return errInvalidImportance(importance)
+	}
+
+	atomic.StoreInt32(&l.accimp, int32(importance)+1)
+
+	return nil
+}
+
+// Close stops the listener. Connections already accepted are not affected.
 func (l *Listener) Close() error {
 	return l.conn.Close()
 }
 
+// CloseGraceful closes the listener, then waits for every connection it
+// accepted to be closed. If ctx expires first, CloseGraceful closes the
+// remaining connections and returns the context's error; otherwise it
+// returns the result of closing the listener.
+func (l *Listener) CloseGraceful(ctx context.Context) error {
+	l.connmu.Lock()
+	l.closing = true
+	l.connmu.Unlock()
+
+	err := l.Close()
+
+	done := make(chan struct{})
+
+	go func() {
+		l.connwg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+	}
+
+	// Closing a connection removes it from l.conns, so the connections are
+	// collected first.
+	l.connmu.Lock()
+	conns := make([]*Conn, 0, len(l.conns))
+	for c := range l.conns {
+		conns = append(conns, c)
+	}
+	l.connmu.Unlock()
+
+	for _, c := range conns {
+		c.Close()
+	}
+
+	return ctx.Err()
+}
+
+// SetDeadline sets the deadline for Accept. An Accept call that is pending
+// when the deadline passes, or that is made after it, fails with a timeout
+// error. A zero value for t disables the deadline.
+func (l *Listener) SetDeadline(t time.Time) error {
+	return l.conn.SetReadDeadline(t)
+}
+
+// Addr returns the service range the listener was created with. The
+// address of the listening socket itself is available from SocketAddr.
 func (l *Listener) Addr() net.Addr {
+	if l.srange == nil {
+		return l.SocketAddr()
+	}
+
+	sr, scope := l.ServiceRange()
+
+	return &Addr{Sockaddr: &unix.SockaddrTIPC{Scope: scope, Addr: sr}, Net: l.conn.network()}
+}
+
+// SocketAddr returns the port address of the listening socket.
+func (l *Listener) SocketAddr() net.Addr {
 	return l.conn.LocalAddr()
 }
 
+// ServiceRange returns the service range and scope the listener was created
+// with.
+func (l *Listener) ServiceRange() (*unix.TIPCServiceRange, int) {
+	if l.srange == nil {
+		return nil, 0
+	}
+
+	sr := *l.srange
+
+	return &sr, l.Scope()
+}
+
+// Scope returns the scope the listener's service range is bound with, which
+// determines where the range is visible: unix.TIPC_NODE_SCOPE for the local
+// node only, or unix.TIPC_CLUSTER_SCOPE or unix.TIPC_ZONE_SCOPE for the
+// whole cluster. It returns 0 for a listener without a known service range,
+// such as one created by ListenerFromFile.
+func (l *Listener) Scope() int {
+	l.bindmu.Lock()
+	defer l.bindmu.Unlock()
+
+	return l.scope
+}
+
+// SetScope changes the scope the listener's service range is bound with.
+//
+// The kernel cannot change the scope of an existing binding, so the range is
+// bound again with the new scope before the old binding is withdrawn. The
+// range stays reachable throughout, and connections already established are
+// not affected.
+func (l *Listener) SetScope(scope int) error {
+	sr, old := l.ServiceRange()
+	if sr == nil {
+		return &net.OpError{Op: "setscope", Net: l.conn.network(), Addr: l.Addr(), Err: errors.New("unknown service range")}
+	}
+
+	if scope == old {
+		return nil
+	}
+
+	if err := l.Publish(scope, sr); err != nil {
+		return err
+	}
+
+	if err := l.Withdraw(old, sr); err != nil {
+		l.Withdraw(scope, sr)
+		return err
+	}
+
+	l.bindmu.Lock()
+	l.scope = scope
+	l.bindmu.Unlock()
+
+	return nil
+}
+
+// Publish binds an additional service range to the listener, so that
+// connections to any of its published ranges are accepted.
+func (l *Listener) Publish(scope int, s *unix.TIPCServiceRange) error {
+	if err := l.conn.bind(&unix.SockaddrTIPC{Scope: scope, Addr: s}); err != nil {
+		return err
+	}
+
+	l.addBinding(scope, s)
+
+	return nil
+}
+
+// Withdraw removes a service range previously bound with Listen or Publish.
+// The scope must match the one used to bind it.
+func (l *Listener) Withdraw(scope int, s *unix.TIPCServiceRange) error {
+	// A negative scope asks the kernel to unbind the range.
+	if err := l.conn.bind(&unix.SockaddrTIPC{Scope: -scope, Addr: s}); err != nil {
+		return err
+	}
+
+	l.bindmu.Lock()
+	defer l.bindmu.Unlock()
+
+	for i, b := range l.bindings {
+		if b.Scope == scope && *b.Range == *s {
+			l.bindings = append(l.bindings[:i], l.bindings[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+// Bindings returns the service ranges the listener is bound to, in the order
+// they were bound: the range given to Listen, if it has not been withdrawn,
+// followed by those added with Publish. The kernel does not report the
+// ranges a socket is bound to, so they are tracked by Publish and Withdraw.
+func (l *Listener) Bindings() ([]Binding, error) {
+	if l.conn.isClosed() {
+		return nil, &net.OpError{Op: "bindings", Net: l.conn.network(), Addr: l.Addr(), Err: net.ErrClosed}
+	}
+
+	l.bindmu.Lock()
+	defer l.bindmu.Unlock()
+
+	bs := make([]Binding, len(l.bindings))
+	for i, b := range l.bindings {
+		sr := *b.Range
+		bs[i] = Binding{Scope: b.Scope, Range: &sr}
+	}
+
+	return bs, nil
+}
+
+func (l *Listener) addBinding(scope int, s *unix.TIPCServiceRange) {
+	sr := *s
+
+	l.bindmu.Lock()
+	l.bindings = append(l.bindings, Binding{Scope: scope, Range: &sr})
+	l.bindmu.Unlock()
+}
+
 type Conn struct {
 	fd        int
 	fil       *os.File
 	sc        syscall.RawConn
 	closeOnce sync.Once
-
-	addrmu sync.Mutex
-	local  *Addr
-	remote *Addr
+	closeErr  error
+
+	addrmu  sync.Mutex
+	local   *Addr
+	remote  *Addr
+	service *unix.TIPCServiceName
+
+	kamu      sync.Mutex
+	kaEnabled bool
+	kaPeriod  time.Duration
+	kaStop    chan struct{}
+	kaErr     error
+
+	groupmu sync.Mutex
+	group   *unix.TIPCGroupReq
+
+	drainmu sync.Mutex
+	drain   bool
+
+	// limit is the read limit set by SetReadLimit, accessed atomically.
+	limit int64
+
+	// rbytes and wbytes count the payload bytes read and written, and
+	// rcalls and wcalls the system calls that read and wrote them,
+	// accessed atomically.
+	rbytes int64
+	wbytes int64
+	rcalls int64
+	wcalls int64
+
+	// trace is the ConnTrace of the Dialer that created the connection.
+	trace *ConnTrace
+
+	// onClose is called once the connection is closed, to remove it from
+	// the Listener that accepted it.
+	onClose func()
+
+	// closed is set to 1 by Close or Abort, accessed atomically.
+	closed int32
+
+	// rawerr is set to 1 by SetRawReadErrors, accessed atomically.
+	rawerr int32
+
+	// typ is the socket type, or 0 if it could not be determined.
+	typ int
+
+	// idle is the timeout set by SetIdleTimeout, idleAt the deadline it
+	// currently imposes, and rdeadline the deadline set by SetDeadline or
+	// SetReadDeadline.
+	idlemu    sync.Mutex
+	idle      time.Duration
+	idleAt    time.Time
+	rdeadline time.Time
 }
 
 func newConn(fd int) (*Conn, error) {
@@ -133,112 +669,351 @@ func newConn(fd int) (*Conn, error) {
 		return nil, err
 	}
 
-	return &Conn{fd: fd, fil: fil, sc: sc}, nil
+	typ, err := unix.GetsockoptInt(fd, unix.SOL_SOCKET, unix.SO_TYPE)
+	if err != nil {
+		typ = 0
+	}
+
+	return &Conn{fd: fd, fil: fil, sc: sc, typ: typ}, nil
+}
+
+// SocketType returns the type of the underlying socket: unix.SOCK_STREAM,
+// unix.SOCK_SEQPACKET, unix.SOCK_RDM or unix.SOCK_DGRAM. The type is read
+// from the socket when the Conn is created, so it is known however the Conn
+// was made, whether dialed, accepted or wrapped from a file. It is 0 in the
+// unlikely case that the kernel did not report it.
+func (tc *Conn) SocketType() int {
+	return tc.typ
+}
+
+// network returns the network name for the connection's socket type.
+func (tc *Conn) network() string {
+	return socketNetwork(tc.typ)
+}
+
+// opNetwork returns the network name reported in errors for a socket of type
+// typ, which is "tipc" if the type is not known.
+func opNetwork(typ int) string {
+	if network := socketNetwork(typ); network != "" {
+		return network
+	}
+
+	return "tipc"
+}
+
+// newAddr returns sa as an address on the connection's network.
+func (tc *Conn) newAddr(sa unix.Sockaddr) *Addr {
+	return &Addr{Sockaddr: sa, Net: tc.network()}
+}
+
+func (tc *Conn) bind(sa *unix.SockaddrTIPC) error {
+	var berr error
+
+	cerr := tc.sc.Control(func(fd uintptr) {
+		berr = unix.Bind(int(fd), sa)
+	})
+
+	if cerr != nil {
+		return tc.opError("bind", cerr)
+	}
+
+	if berr != nil {
+		return tc.opError("bind", os.NewSyscallError("bind", berr))
+	}
+
+	return nil
 }
 
 func (c *Conn) String() string {
 	return fmt.Sprintf("%s -> %s", c.LocalAddr(), c.RemoteAddr())
 }
 
-func (tc *Conn) Read(b []byte) (n int, err error) {
-	n, err = tc.fil.Read(b)
+// ErrConnAbort is returned by Read when a connection was aborted rather than
+// closed by the peer, for example because the peer node became unreachable.
+var ErrConnAbort = errors.New("tipc: connection aborted")
+
+// Read reads data from the connection.
+//
+// When the connection has been closed, Read returns io.EOF if the peer shut
+// the connection down or closed its socket, and an error wrapping
+// ErrConnAbort if the connection was aborted for any other reason, such as
+// the loss of the peer node.
+func (tc *Conn) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
 
-	if err != nil {
-		var perr *os.PathError
-		if errors.As(err, &perr) {
-			// XXX: io.Copy and friends expect io.EOF to cleanly
-			// terminate, and tipc seems to indicate that with
-			// ECONNRESET...
-			if perr.Err == syscall.ECONNRESET {
-				return n, io.EOF
-			}
-		}
+	bp := rejectOOBPool.Get().(*[]byte)
+	defer rejectOOBPool.Put(bp)
 
-		operr := &net.OpError{
-			Op:     "read",
-			Net:    "tipc",
-			Source: tc.LocalAddr(),
-			Addr:   tc.RemoteAddr(),
-			Err:    err,
-		}
+	oob := *bp
 
-		return n, operr
+	n, oobn, _, _, err := tc.recvmsg(b, oob, 0)
+	if err == nil && n > 0 {
+		tc.recordService(oob[:oobn])
+		return n, nil
 	}
 
-	return
+	if kaerr := tc.keepAliveErr(); kaerr != nil {
+		return n, tc.opError("read", kaerr)
+	}
+
+	if err == nil {
+		return 0, tc.closeError(oob[:oobn])
+	}
+
+	// XXX: io.Copy and friends expect io.EOF to cleanly terminate, and
+	// tipc indicates a closed connection with ECONNRESET when it has no
+	// control buffer to report the reason in...
+	if errors.Is(err, unix.ECONNRESET) {
+		return 0, tc.resetError()
+	}
+
+	if _, ok := err.(syscall.Errno); ok {
+		err = os.NewSyscallError("recvmsg", err)
+	}
+
+	return 0, tc.opError("read", err)
 }
 
-func (tc *Conn) Write(b []byte) (n int, err error) {
-	n, err = tc.fil.Write(b)
+// closeError returns the error for a zero-length read with the control
+// messages in oob. A shutdown or closed peer is a normal end of stream; any
+// other reason is an abort.
+func closeError(oob []byte) error {
+	rerr := parseRejection(oob)
+	if rerr == nil {
+		return io.EOF
+	}
 
-	if err != nil {
-		operr := &net.OpError{
-			Op:     "write",
-			Net:    "tipc",
-			Source: tc.LocalAddr(),
-			Addr:   tc.RemoteAddr(),
-			Err:    err,
-		}
+	switch rerr.Code {
+	case unix.TIPC_CONN_SHUTDOWN, unix.TIPC_ERR_NO_PORT:
+		return io.EOF
+	}
+
+	return ErrConnAbort
+}
 
-		return 0, operr
+// SetRawReadErrors controls how reads report a peer that closed its socket
+// without shutting the connection down first. By default such a close is a
+// normal end of stream and reads return io.EOF, as io.Copy expects. When on
+// is true, reads instead return an error wrapping unix.ECONNRESET, so that
+// the application can tell a reset from a clean end of stream, for example
+// to reconnect. A peer that called CloseWrite, or closed with
+// SetDrainOnClose, still results in io.EOF.
+func (tc *Conn) SetRawReadErrors(on bool) {
+	var v int32
+	if on {
+		v = 1
 	}
 
-	return
+	atomic.StoreInt32(&tc.rawerr, v)
 }
 
-func (tc *Conn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
-	/*
-		// TODO: finish.
-		var (
-			iov unix.Iovec
-			msg unix.Msghdr
-			//sa        unix.RawSockaddrTIPC
-			ancillary []byte
-		)
+// closeError is like the function closeError, but reports a closed peer as a
+// reset if SetRawReadErrors is on, and wraps errors other than io.EOF.
+func (tc *Conn) closeError(oob []byte) error {
+	err := closeError(oob)
+	if err != io.EOF {
+		return tc.opError("read", err)
+	}
 
-		ancillary = make([]byte, unix.CmsgSpace(8)+unix.CmsgSpace(1024)+unix.CmsgSpace(16))
+	if rerr := parseRejection(oob); rerr != nil && rerr.Code == unix.TIPC_ERR_NO_PORT {
+		return tc.resetError()
+	}
 
-		iov.Base = &p[0]
-		iov.SetLen(len(p))
+	return io.EOF
+}
 
-		msg.Iov = &iov
-		msg.Iovlen = 1
+// resetError returns the error for a read that found the connection reset:
+// io.EOF, or ECONNRESET if SetRawReadErrors is on.
+func (tc *Conn) resetError() error {
+	if atomic.LoadInt32(&tc.rawerr) != 0 {
+		return tc.opError("read", os.NewSyscallError("recvmsg", unix.ECONNRESET))
+	}
 
-		msg.Control = &ancillary[0]
-		msg.SetControllen(len(ancillary))
+	return io.EOF
+}
 
-		return 0, nil, nil
-	*/
-	var (
-		nn   int
-		sa   unix.Sockaddr
-		rerr error
-	)
+// Write writes b to the connection. On a stream connection, a write that is
+// interrupted by the write deadline or an error after sending part of b
+// returns the number of bytes sent along with the error.
+func (tc *Conn) Write(b []byte) (n int, err error) {
+	cerr := tc.sc.Write(func(fd uintptr) bool {
+		for {
+			var m int
+
+			err = ignoringEINTR(func() (err error) {
+				m, err = unix.Write(int(fd), b[n:])
+				return
+			})
+
+			if m > 0 {
+				n += m
+			}
+
+			if err != nil {
+				return !errors.Is(err, unix.EAGAIN)
+			}
+
+			tc.countWrite(m)
 
-	cerr := tc.sc.Read(func(fd uintptr) bool {
-		nn, sa, rerr = unix.Recvfrom(int(fd), p, 0)
-		return !errors.Is(rerr, syscall.EAGAIN)
+			if n == len(b) {
+				return true
+			}
+		}
 	})
 
 	if cerr != nil {
-		return 0, nil, cerr
+		return n, tc.opError("write", cerr)
 	}
 
-	if rerr != nil {
-		return 0, nil, rerr
+	if err != nil {
+		return n, tc.opError("write", os.NewSyscallError("write", err))
 	}
 
-	return nn, &Addr{sa}, nil
+	return n, nil
 }
 
+// WriteString is like Write, but writes the contents of s without copying it
+// into a byte slice first. It implements io.StringWriter, which io.WriteString
+// and fmt use to avoid the conversion.
+func (tc *Conn) WriteString(s string) (int, error) {
+	n, err := tc.Write(stringBytes(s))
+	runtime.KeepAlive(s)
+	return n, err
+}
+
+// stringBytes returns a byte slice sharing the memory of s. The slice must
+// not be modified, and s must be kept alive for as long as it is in use.
+func stringBytes(s string) []byte {
+	var b []byte
+
+	sh := (*reflect.StringHeader)(unsafe.Pointer(&s))
+	bh := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	bh.Data = sh.Data
+	bh.Len = sh.Len
+	bh.Cap = sh.Len
+
+	return b
+}
+
+// ReadFrom reads a message from the socket. The returned address is the port
+// identity of the sending socket, which may be passed to WriteTo to reply to
+// that socket directly, even if the message was sent to a service name. If
+// the message was one sent from this socket and rejected by the destination,
+// ReadFrom returns a *RejectedError and the address the message was sent to.
+//
+// If a read limit is set with SetReadLimit, a message longer than the limit
+// is discarded and ReadFrom returns ErrMessageTooLarge with its source,
+// whatever the length of p. Otherwise, as with a UDP socket, a message longer
+// than p is truncated without an error; ReadMessage reports truncation.
+func (tc *Conn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	bp := returnOOBPool.Get().(*[]byte)
+	defer returnOOBPool.Put(bp)
+
+	oob := *bp
+
+	// TIPC only reports a message as truncated relative to the buffer it
+	// is read into, so a limited read needs a buffer of exactly the limit.
+	// If p is not longer, the message is read into a pooled buffer of that
+	// size and copied.
+	buf := p
+	scratch := false
+	limit := tc.readLimit()
+
+	if limit > 0 {
+		if len(p) > limit {
+			buf = p[:limit]
+		} else {
+			lp := limitBufPool.Get().(*[]byte)
+			defer limitBufPool.Put(lp)
+
+			if cap(*lp) < limit {
+				*lp = make([]byte, limit)
+			}
+
+			buf = (*lp)[:limit]
+			scratch = true
+		}
+	}
+
+	n, oobn, flags, sa, err := tc.recvmsg(buf, oob, 0)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if sa != nil {
+		addr = tc.newAddr(sa)
+	}
+
+	if rerr := parseRejection(oob[:oobn]); rerr != nil {
+		return 0, addr, rerr
+	}
+
+	if limit > 0 && flags&unix.MSG_TRUNC != 0 {
+		return 0, addr, ErrMessageTooLarge
+	}
+
+	if scratch {
+		n = copy(p, buf[:n])
+	}
+
+	return n, addr, nil
+}
+
+// limitBufPool holds the buffers ReadFrom reads limited messages into when
+// the caller's buffer is not longer than the limit.
+var limitBufPool = sync.Pool{
+	New: func() interface{} {
+		return new([]byte)
+	},
+}
+
+// ErrMessageTooLarge is returned by ReadFrom for a message exceeding the
+// connection's read limit.
+var ErrMessageTooLarge = errors.New("tipc: message exceeds read limit")
+
+// SetReadLimit sets the maximum size of messages accepted by ReadFrom. Longer
+// messages are discarded, and reported with ErrMessageTooLarge instead of
+// being silently truncated. A limit of zero or less removes the limit.
+//
+// TIPC already bounds messages to 66000 bytes, so the limit mainly serves
+// services that expect much smaller requests.
+func (tc *Conn) SetReadLimit(n int) {
+	if n < 0 {
+		n = 0
+	}
+
+	atomic.StoreInt64(&tc.limit, int64(n))
+}
+
+func (tc *Conn) readLimit() int {
+	return int(atomic.LoadInt64(&tc.limit))
+}
+
+// ErrNotPacketConn is returned by WriteTo and the methods built on it when
+// called on a stream or seqpacket connection, which can only send to its
+// peer, with Write.
+var ErrNotPacketConn = errors.New("tipc: addressed send on a connection-oriented socket")
+
+// WriteTo sends p to addr, which may be a service name, a service range for
+// multicast, or the port identity of a single socket, such as an address
+// returned by ReadFrom. It is only valid on SOCK_RDM and SOCK_DGRAM sockets,
+// and returns an error wrapping ErrNotPacketConn on others.
 func (tc *Conn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	if err := tc.checkPacketConn(); err != nil {
+		return 0, err
+	}
+
 	ta, ok := addr.(*Addr)
 	if !ok {
 		return 0, &net.AddrError{Err: "expected tipc.Addr", Addr: addr.String()}
 	}
 
 	cerr := tc.sc.Write(func(fd uintptr) bool {
-		err = unix.Sendto(int(fd), p, 0, ta.Sockaddr)
+		err = ignoringEINTR(func() error {
+			return unix.Sendto(int(fd), p, 0, ta.Sockaddr)
+		})
 		return !errors.Is(err, syscall.EAGAIN)
 	})
 
@@ -250,68 +1025,330 @@ func (tc *Conn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
 		return 0, err
 	}
 
+	tc.countWrite(len(p))
+
 	return len(p), nil
 }
 
-func (tc *Conn) Close() (err error) {
-	return tc.fil.Close()
+// checkPacketConn returns an error if the socket is known to be connection
+// oriented.
+func (tc *Conn) checkPacketConn() error {
+	switch tc.typ {
+	case unix.SOCK_STREAM, unix.SOCK_SEQPACKET:
+		return tc.opError("write", ErrNotPacketConn)
+	}
+
+	return nil
 }
 
-func (tc *Conn) LocalAddr() net.Addr {
-	tc.addrmu.Lock()
-	defer tc.addrmu.Unlock()
+// Multicast sends p to every socket bound to a service range overlapping s
+// within scope. By default the kernel chooses between L2 broadcast and
+// replicated unicast (replicast) delivery based on the number of destination
+// nodes; SetMulticastMethod can be used to force one or the other.
+func (tc *Conn) Multicast(p []byte, scope int, s *unix.TIPCServiceRange) (int, error) {
+	sa := &unix.SockaddrTIPC{
+		Scope: scope,
+		Addr:  s,
+	}
+
+	return tc.WriteTo(p, &Addr{Sockaddr: sa})
+}
+
+// Broadcast sends p to every socket in the cluster bound to any instance of
+// the service type typ. It is a Multicast to the range {typ, 0, 2^32-1} at
+// cluster scope, and each socket receives a single copy however many
+// matching ranges it is bound to.
+//
+// Broadcast only determines the destination sockets, not how the message
+// reaches their nodes: as with Multicast, the kernel picks L2 broadcast or
+// replicast per message unless SetMulticastMethod forces one. Nodes without
+// a socket bound to typ receive nothing, so every node that should hear the
+// message must bind a socket to the type.
+func (tc *Conn) Broadcast(typ uint32, p []byte) (int, error) {
+	return tc.Multicast(p, unix.TIPC_CLUSTER_SCOPE, &unix.TIPCServiceRange{Type: typ, Lower: 0, Upper: ^uint32(0)})
+}
 
-	if tc.local != nil {
-		return tc.local
+// Anycast sends p to a single socket bound to the service name {typ,
+// instance}. domain limits the lookup to a node or cluster address, or is 0
+// to select any matching socket in the cluster, with local sockets
+// preferred.
+func (tc *Conn) Anycast(p []byte, typ, instance, domain uint32) (int, error) {
+	sa := &unix.SockaddrTIPC{
+		Scope: unix.TIPC_CLUSTER_SCOPE,
+		Addr: &unix.TIPCServiceName{
+			Type:     typ,
+			Instance: instance,
+			Domain:   domain,
+		},
 	}
 
-	sa, err := unix.Getsockname(tc.fd)
-	if err != nil {
-		return nil
+	return tc.WriteTo(p, &Addr{Sockaddr: sa})
+}
+
+// Close closes the connection. It is safe to call Close more than once and
+// from multiple goroutines; every call returns the result of the first.
+//
+// Data already written is not discarded: TIPC hands messages to the link
+// layer on Write, and the link delivers them in order ahead of the
+// disconnect. See SetDrainOnClose for an orderly shutdown before closing.
+func (tc *Conn) Close() error {
+	tc.closeOnce.Do(func() {
+		atomic.StoreInt32(&tc.closed, 1)
+		tc.stopKeepAlive()
+
+		tc.drainmu.Lock()
+		drain := tc.drain
+		tc.drainmu.Unlock()
+
+		if drain {
+			// The peer may already have gone away; the close still
+			// proceeds.
+			tc.shutdown()
+		}
+
+		tc.closeErr = tc.fil.Close()
+		tc.runCloseHooks()
+	})
+
+	return tc.closeErr
+}
+
+// runCloseHooks runs the hooks for a closed connection.
+func (tc *Conn) runCloseHooks() {
+	tc.traceClosed()
+
+	if tc.onClose != nil {
+		tc.onClose()
 	}
-	tc.local = &Addr{sa}
+}
+
+func (tc *Conn) isClosed() bool {
+	return atomic.LoadInt32(&tc.closed) != 0
+}
+
+// SetDrainOnClose makes Close shut the connection down before closing the
+// socket. The shutdown message follows the written data over the same
+// link, so the peer reads everything written followed by io.EOF, with the
+// orderly TIPC_CONN_SHUTDOWN reason rather than the TIPC_ERR_NO_PORT reason
+// sent for a released socket. Abort ignores this setting.
+func (tc *Conn) SetDrainOnClose(on bool) {
+	tc.drainmu.Lock()
+	tc.drain = on
+	tc.drainmu.Unlock()
+}
+
+// Abort closes the connection without shutting it down first, even if
+// SetDrainOnClose is on. Like Close, it is safe to call more than once, and
+// calls after the connection is closed have no effect.
+//
+// TIPC has no reset message, and ignores SO_LINGER. What distinguishes an
+// abort is the reason the kernel disconnects the peer with: TIPC_ERR_NO_PORT
+// for a released socket, rather than the TIPC_CONN_SHUTDOWN of a drained
+// close. A peer using this package reads io.EOF for either by default, and an
+// error wrapping unix.ECONNRESET for an abort if it enabled SetRawReadErrors.
+// Unread messages queued on this side are rejected back to the peer.
+func (tc *Conn) Abort() error {
+	tc.closeOnce.Do(func() {
+		atomic.StoreInt32(&tc.closed, 1)
+		tc.stopKeepAlive()
+
+		tc.closeErr = tc.fil.Close()
+		tc.runCloseHooks()
+	})
 
-	return tc.local
+	return tc.closeErr
+}
 
-	return &Addr{sa}
+// CloseRead shuts down the reading side of the connection.
+//
+// TIPC does not support half-closed connections, and the kernel only accepts
+// a shutdown of both directions. CloseRead and CloseWrite therefore both
+// disconnect the connection: the peer reads io.EOF, and further reads and
+// writes on this side fail. The file descriptor remains open until Close.
+func (tc *Conn) CloseRead() error {
+	return tc.shutdown()
 }
 
+// CloseWrite shuts down the writing side of the connection. See CloseRead
+// for how this differs from TCP.
+func (tc *Conn) CloseWrite() error {
+	return tc.shutdown()
+}
+
+func (tc *Conn) shutdown() error {
+	var serr error
+
+	cerr := tc.sc.Control(func(fd uintptr) {
+		serr = unix.Shutdown(int(fd), unix.SHUT_RDWR)
+	})
+
+	if cerr != nil {
+		return tc.opError("shutdown", cerr)
+	}
+
+	if serr != nil {
+		return tc.opError("shutdown", os.NewSyscallError("shutdown", serr))
+	}
+
+	return nil
+}
+
+// LocalAddr returns the port identity of the socket, or nil if it cannot be
+// determined, for instance because the socket is closed.
+func (tc *Conn) LocalAddr() net.Addr {
+	if a := tc.cachedAddr(&tc.local, unix.Getsockname); a != nil {
+		return a
+	}
+
+	return nil
+}
+
+// RemoteAddr returns the port identity of the connected peer, or nil if the
+// socket is not connected.
 func (tc *Conn) RemoteAddr() net.Addr {
+	if a := tc.cachedAddr(&tc.remote, unix.Getpeername); a != nil {
+		return a
+	}
+
+	return nil
+}
+
+// cachedAddr returns *cache, filling it in with get first if it is not yet
+// set. The cache is only written once get has succeeded, so a failed lookup
+// is retried by the next call. It returns nil on failure.
+func (tc *Conn) cachedAddr(cache **Addr, get func(fd int) (unix.Sockaddr, error)) *Addr {
 	tc.addrmu.Lock()
 	defer tc.addrmu.Unlock()
 
-	if tc.remote != nil {
-		return tc.remote
+	if *cache != nil {
+		return *cache
 	}
 
-	sa, err := unix.Getpeername(tc.fd)
-	if err != nil {
+	var (
+		sa  unix.Sockaddr
+		err error
+	)
+
+	// Going through the RawConn keeps the descriptor from being closed,
+	// and possibly reused, during the call.
+	cerr := tc.sc.Control(func(fd uintptr) {
+		sa, err = get(int(fd))
+	})
+
+	if cerr != nil || err != nil {
 		return nil
 	}
 
-	tc.remote = &Addr{sa}
+	*cache = tc.newAddr(sa)
 
-	return tc.remote
+	return *cache
 }
 
+// SetDeadline sets the read and write deadlines of the connection.
+//
+// Deadlines apply to every read and write, including ReadFrom, WriteTo and
+// the other methods that use the socket directly: they all wait for the
+// socket through the runtime poller, which fails the wait with an error
+// wrapping os.ErrDeadlineExceeded once the deadline has passed.
 func (tc *Conn) SetDeadline(t time.Time) error {
-	return tc.fil.SetDeadline(t)
+	if err := tc.setReadDeadline(t); err != nil {
+		return err
+	}
+
+	return tc.fil.SetWriteDeadline(t)
 }
 
+// SetReadDeadline sets the deadline for reads. See SetDeadline.
 func (tc *Conn) SetReadDeadline(t time.Time) error {
-	return tc.fil.SetReadDeadline(t)
+	return tc.setReadDeadline(t)
 }
 
+// SetWriteDeadline sets the deadline for writes. See SetDeadline.
 func (tc *Conn) SetWriteDeadline(t time.Time) error {
 	return tc.fil.SetWriteDeadline(t)
 }
 
-func newConnectConn(typ int, s *unix.SockaddrTIPC) (*Conn, error) {
-	fd, err := unix.Socket(unix.AF_TIPC, typ|unix.SOCK_CLOEXEC, 0)
+// SyscallConn returns a raw network connection. This implements the
+// syscall.Conn interface.
+func (tc *Conn) SyscallConn() (syscall.RawConn, error) {
+	return tc.sc, nil
+}
+
+// File returns a copy of the underlying file. The returned file refers to a
+// duplicate of the socket, so closing it does not affect the Conn and closing
+// the Conn does not affect it. Unlike the Conn, the returned file is in
+// blocking mode.
+func (tc *Conn) File() (*os.File, error) {
+	nfd, err := tc.dup("file")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := unix.SetNonblock(nfd, false); err != nil {
+		unix.Close(nfd)
+		return nil, tc.opError("file", os.NewSyscallError("setnonblock", err))
+	}
+
+	return os.NewFile(uintptr(nfd), "tipc"), nil
+}
+
+// Clone returns a new Conn referring to a duplicate of the socket, so that
+// the two can be closed independently. Both share the socket's state, such
+// as its receive queue and options; Conn-level settings, such as deadlines
+// and keepalives, are not copied.
+func (tc *Conn) Clone() (*Conn, error) {
+	nfd, err := tc.dup("clone")
 	if err != nil {
 		return nil, err
 	}
 
+	// The duplicate shares the original's file status flags, so it is
+	// already in non-blocking mode.
+	c, err := newConn(nfd)
+	if err != nil {
+		return nil, tc.opError("clone", err)
+	}
+
+	return c, nil
+}
+
+// dup returns a close-on-exec duplicate of the socket.
+func (tc *Conn) dup(op string) (int, error) {
+	var (
+		nfd  int
+		derr error
+	)
+
+	cerr := tc.sc.Control(func(fd uintptr) {
+		nfd, derr = unix.FcntlInt(fd, unix.F_DUPFD_CLOEXEC, 0)
+	})
+
+	if cerr != nil {
+		return -1, tc.opError(op, cerr)
+	}
+
+	if derr != nil {
+		return -1, tc.opError(op, os.NewSyscallError("fcntl", derr))
+	}
+
+	return nfd, nil
+}
+
+// newConnectConn creates a socket of type typ and connects it to s. If setup
+// is not nil, it is called with the socket before connecting.
+func newConnectConn(typ int, s *unix.SockaddrTIPC, setup func(fd int) error) (*Conn, error) {
+	fd, err := socket(typ)
+	if err != nil {
+		return nil, err
+	}
+
+	if setup != nil {
+		if err := setup(fd); err != nil {
+			unix.Close(fd)
+			return nil, err
+		}
+	}
+
 	if err := unix.Connect(fd, s); err != nil {
 		unix.Close(fd)
 		return nil, err
@@ -326,15 +1363,47 @@ func newConnectConn(typ int, s *unix.SockaddrTIPC) (*Conn, error) {
 }
 
 func DialSequentialPacket(s *unix.SockaddrTIPC) (*Conn, error) {
-	return newConnectConn(unix.SOCK_SEQPACKET, s)
+	return newConnectConn(unix.SOCK_SEQPACKET, s, nil)
 }
 
 func DialStream(s *unix.SockaddrTIPC) (*Conn, error) {
-	return newConnectConn(unix.SOCK_STREAM, s)
+	return newConnectConn(unix.SOCK_STREAM, s, nil)
+}
+
+// DialStreamImportance is like DialStream, but sets the TIPC_IMPORTANCE
+// option before connecting, so that the connection setup messages are sent
+// at importance as well as the data. importance ranges from
+// unix.TIPC_LOW_IMPORTANCE to unix.TIPC_CRITICAL_IMPORTANCE.
+func DialStreamImportance(s *unix.SockaddrTIPC, importance int) (*Conn, error) {
+	if !validImportance(importance) {
+		return nil, errInvalidImportance(importance)
+	}
+
+	return newConnectConn(unix.SOCK_STREAM, s, func(fd int) error {
+		err := unix.SetsockoptInt(fd, unix.SOL_TIPC, unix.TIPC_IMPORTANCE, importance)
+		return os.NewSyscallError("setsockopt", err)
+	})
+}
+
+func validImportance(importance int) bool {
+	return importance >= unix.TIPC_LOW_IMPORTANCE && importance <= unix.TIPC_CRITICAL_IMPORTANCE
+}
+
+func errInvalidImportance(importance int) error {
+	return fmt.Errorf("tipc: invalid importance %d", importance)
+}
+
+var errMissingAddress = &net.AddrError{Err: "missing address"}
+
+// DialAddr connects to addr on the named network. Known networks are
+// "tipc-stream" and "tipc-seqpacket".
+func DialAddr(network string, addr *Addr) (*Conn, error) {
+	var d Dialer
+	return d.Dial(network, addr)
 }
 
 func newPacketConn(typ int, s *unix.SockaddrTIPC, bind bool) (*Conn, error) {
-	fd, err := unix.Socket(unix.AF_TIPC, typ|unix.SOCK_CLOEXEC, 0)
+	fd, err := socket(typ)
 	if err != nil {
 		return nil, err
 	}
@@ -354,13 +1423,45 @@ func newPacketConn(typ int, s *unix.SockaddrTIPC, bind bool) (*Conn, error) {
 	return newConn(fd)
 }
 
+// ListenReliableDatagram returns a SOCK_RDM socket bound to s.
+//
+// RDM ("reliable datagram") and DGRAM sockets both send connectionless
+// messages of up to 66000 bytes, and both preserve message boundaries: each
+// read returns exactly one message, which is never split or merged with
+// another. Messages between a pair of sockets arrive in the order they were
+// sent, since TIPC links deliver in order, but there is no ordering between
+// messages from different senders.
+//
+// The two differ in what happens when delivery fails. An RDM socket waits
+// for the link when it is congested rather than dropping messages, and a
+// message that cannot be delivered, for example because the destination
+// socket is gone, is returned to the sender and reported by ReadFrom or
+// ReadMessage as a *RejectedError, unless SetDestDroppable is set. A DGRAM
+// socket is created with TIPC_SRC_DROPPABLE set, so messages may be dropped
+// silently under congestion; see SetSrcDroppable.
+//
+// To detect messages larger than the read buffer, which are truncated and
+// have their remainder discarded, use ReadMessage, or SetReadLimit with
+// ReadFrom.
 func ListenReliableDatagram(s *unix.SockaddrTIPC) (*Conn, error) {
 	return newPacketConn(unix.SOCK_RDM, s, true)
 }
+
+// ListenDatagram returns a SOCK_DGRAM socket bound to s. See
+// ListenReliableDatagram for how DGRAM sockets differ from RDM sockets.
 func ListenDatagram(s *unix.SockaddrTIPC) (*Conn, error) {
 	return newPacketConn(unix.SOCK_DGRAM, s, true)
 }
 
+// ListenDatagramAny returns an unbound SOCK_DGRAM socket. Every TIPC socket
+// is assigned a port identity when created, so LocalAddr returns an address
+// that peers can use as a reply destination without any service being bound.
+func ListenDatagramAny() (*Conn, error) {
+	return newPacketConn(unix.SOCK_DGRAM, nil, false)
+}
+
+// ReliableDatagram returns an unbound SOCK_RDM socket, for sending messages
+// and receiving replies. See ListenReliableDatagram for RDM semantics.
 func ReliableDatagram() (*Conn, error) {
 	return newPacketConn(unix.SOCK_RDM, nil, false)
 }
@@ -370,7 +1471,7 @@ func ReliableDatagram() (*Conn, error) {
 func SocketPair() (c1, c2 *Conn, err error) {
 	fds, err := unix.Socketpair(unix.AF_TIPC, unix.SOCK_SEQPACKET|syscall.SOCK_CLOEXEC, 0)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, socketError(err)
 	}
 
 	if err := unix.SetNonblock(fds[0], true); err != nil {
@@ -1,21 +1,47 @@
 package tipc
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"testing/iotest"
+	"time"
+	"unsafe"
 
 	"golang.org/x/net/nettest"
 	"golang.org/x/sys/unix"
 )
 
 func pipemaker() (c1, c2 net.Conn, stop func(), err error) {
+	return makePipe(Listen, DialStream, 999)
+}
+
+func seqpacketPipemaker() (c1, c2 net.Conn, stop func(), err error) {
+	return makePipe(ListenSequentialPacket, DialSequentialPacket, 998)
+}
+
+func makePipe(
+	listen func(int, *unix.TIPCServiceRange) (*Listener, error),
+	dial func(*unix.SockaddrTIPC) (*Conn, error),
+	typ uint32,
+) (c1, c2 net.Conn, stop func(), err error) {
 	sr := &unix.TIPCServiceRange{
-		Type:  999,
+		Type:  typ,
 		Lower: 0,
 		Upper: ^uint32(0),
 	}
 
-	l, err := Listen(unix.TIPC_CLUSTER_SCOPE, sr)
+	l, err := listen(unix.TIPC_CLUSTER_SCOPE, sr)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -32,7 +58,7 @@ func pipemaker() (c1, c2 net.Conn, stop func(), err error) {
 	}()
 
 	sa := &unix.TIPCServiceName{
-		Type:     999,
+		Type:     typ,
 		Instance: 0,
 		Domain:   0,
 	}
@@ -42,7 +68,7 @@ func pipemaker() (c1, c2 net.Conn, stop func(), err error) {
 		Addr:  sa,
 	}
 
-	c1, err = DialStream(st)
+	c1, err = dial(st)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -62,6 +88,103 @@ func TestNetConn(t *testing.T) {
 	nettest.TestConn(t, pipemaker)
 }
 
+func TestSequentialPacketConn(t *testing.T) {
+	nettest.TestConn(t, seqpacketPipemaker)
+}
+
+// testPacketRoundTrip sends messages from a client socket to a server bound
+// to a service and checks each arrives intact, then replies to the client's
+// address.
+func testPacketRoundTrip(t *testing.T, server, client *Conn, dst *Addr) {
+	msgs := []string{"one", "two", "three"}
+
+	for _, m := range msgs {
+		if n, err := client.WriteTo([]byte(m), dst); err != nil {
+			t.Fatal(err)
+		} else if n != len(m) {
+			t.Errorf("wrote %d bytes, want %d", n, len(m))
+		}
+	}
+
+	buf := make([]byte, 64)
+
+	var from net.Addr
+
+	for _, m := range msgs {
+		n, addr, err := server.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(buf[:n]) != m {
+			t.Errorf("got %q, want %q", buf[:n], m)
+		}
+
+		from = addr
+	}
+
+	// The source is the client's port identity, not the service it bound.
+	if _, ok := from.(*Addr).PortRef(); !ok {
+		t.Fatalf("source %v is not a port identity", from)
+	}
+
+	if from.String() != client.LocalAddr().String() {
+		t.Errorf("source %v, want %v", from, client.LocalAddr())
+	}
+
+	if _, err := server.WriteTo([]byte("reply"), from); err != nil {
+		t.Fatal(err)
+	}
+
+	n, _, err := client.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "reply" {
+		t.Errorf("got reply %q", buf[:n])
+	}
+}
+
+func TestDatagramRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		listen func(*unix.SockaddrTIPC) (*Conn, error)
+		typ    uint32
+	}{
+		{"dgram", ListenDatagram, 1011},
+		{"rdm", ListenReliableDatagram, 1012},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, err := tt.listen(&unix.SockaddrTIPC{
+				Scope: unix.TIPC_CLUSTER_SCOPE,
+				Addr:  &unix.TIPCServiceRange{Type: tt.typ, Lower: 0, Upper: 10},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer server.Close()
+
+			client, err := ReliableDatagram()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer client.Close()
+
+			dst := &Addr{Sockaddr: &unix.SockaddrTIPC{
+				Scope: unix.TIPC_CLUSTER_SCOPE,
+				Addr:  &unix.TIPCServiceName{Type: tt.typ, Instance: 1},
+			}}
+
+			testPacketRoundTrip(t, server, client, dst)
+		})
+	}
+}
+
 func TestSocketPair(t *testing.T) {
 	socketpair := func() (net.Conn, net.Conn, func(), error) {
 		c1, c2, err := SocketPair()
@@ -79,3 +202,4122 @@ func TestSocketPair(t *testing.T) {
 
 	nettest.TestConn(t, socketpair)
 }
+
+var _ MessageConn = (*Conn)(nil)
+
+func TestPipeConformance(t *testing.T) {
+	nettest.TestConn(t, func() (net.Conn, net.Conn, func(), error) {
+		c1, c2 := Pipe()
+
+		stop := func() {
+			c2.Close()
+			c1.Close()
+		}
+
+		return c1, c2, stop, nil
+	})
+}
+
+func TestPipe(t *testing.T) {
+	c1, c2 := Pipe()
+
+	defer c2.Close()
+	defer c1.Close()
+
+	for _, m := range []string{"first", "second message"} {
+		if _, err := c1.Write([]byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := make([]byte, 64)
+
+	if n, err := c2.Read(buf); err != nil || string(buf[:n]) != "first" {
+		t.Errorf("Read = %q, %v, want first", buf[:n], err)
+	}
+
+	n, truncated, err := c2.ReadMessage(buf[:6])
+	if err != nil || string(buf[:n]) != "second" || !truncated {
+		t.Errorf("ReadMessage = %q, %v, %v, want truncated second", buf[:n], truncated, err)
+	}
+
+	c2.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+
+	if _, err := c2.Read(buf); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Read past deadline returned %v", err)
+	}
+
+	c2.SetReadDeadline(time.Time{})
+
+	if c1.LocalAddr().String() != c2.RemoteAddr().String() || c1.LocalAddr().String() == c2.LocalAddr().String() {
+		t.Errorf("addresses %v %v, %v %v", c1.LocalAddr(), c1.RemoteAddr(), c2.LocalAddr(), c2.RemoteAddr())
+	}
+
+	// Messages written before Close are still read by the peer.
+	if _, err := c1.Write([]byte("last")); err != nil {
+		t.Fatal(err)
+	}
+
+	c1.Close()
+
+	if n, err := c2.Read(buf); err != nil || string(buf[:n]) != "last" {
+		t.Errorf("Read after peer Close = %q, %v, want last", buf[:n], err)
+	}
+
+	if _, err := c2.Read(buf); err != io.EOF {
+		t.Errorf("Read after queued messages = %v, want %v", err, io.EOF)
+	}
+
+	if _, err := c2.Write([]byte("gone")); !errors.Is(err, unix.EPIPE) {
+		t.Errorf("Write to closed peer = %v, want %v", err, unix.EPIPE)
+	}
+
+	_, err = c1.Read(buf)
+	if !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Read after Close = %v, want %v", err, net.ErrClosed)
+	}
+
+	var operr *net.OpError
+	if !errors.As(err, &operr) || operr.Net != c1.LocalAddr().Network() {
+		t.Errorf("Read after Close = %#v, want the network of %v", err, c1.LocalAddr())
+	}
+}
+
+func ExamplePipe() {
+	// greet is the code under test. Written against MessageConn, it is
+	// served by a *Conn in production and by Pipe in tests.
+	greet := func(c MessageConn) error {
+		buf := make([]byte, 64)
+
+		n, err := c.Read(buf)
+		if err != nil {
+			return err
+		}
+
+		_, err = c.Write(append([]byte("hello, "), buf[:n]...))
+
+		return err
+	}
+
+	client, server := Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go greet(server)
+
+	if _, err := client.Write([]byte("world")); err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	buf := make([]byte, 64)
+
+	n, err := client.Read(buf)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println(string(buf[:n]))
+	// Output: hello, world
+}
+
+func TestAddrConcurrent(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+	defer c1.Close()
+
+	want := [2]string{c1.LocalAddr().String(), c2.LocalAddr().String()}
+
+	var wg sync.WaitGroup
+
+	// Fresh connections, so that the goroutines race to fill the cache.
+	for _, c := range []*Conn{c1, c2} {
+		c.addrmu.Lock()
+		c.local, c.remote = nil, nil
+		c.addrmu.Unlock()
+	}
+
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				la, ra := c1.LocalAddr(), c1.RemoteAddr()
+				if la == nil || ra == nil {
+					t.Error("nil address")
+					return
+				}
+
+				if la.String() != want[0] || ra.String() != want[1] {
+					t.Errorf("got %s -> %s, want %s -> %s", la, ra, want[0], want[1])
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	c1.Close()
+
+	// After Close, the cached addresses are still returned.
+	if la := c1.LocalAddr(); la == nil || la.String() != want[0] {
+		t.Errorf("LocalAddr after Close = %v, want %s", la, want[0])
+	}
+}
+
+func TestRecvQDepth(t *testing.T) {
+	c, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	depth, err := c.SockRecvQDepth()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if depth != 0 {
+		t.Errorf("socket queue depth = %d, want 0", depth)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.WriteTo([]byte("queued"), c.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	depth, err = c.SockRecvQDepth()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if depth != 2 {
+		t.Errorf("socket queue depth = %d with 2 messages queued", depth)
+	}
+
+	depth, err = c.NodeRecvQDepth()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if depth != 0 {
+		t.Errorf("node queue depth = %d, want 0", depth)
+	}
+}
+
+func TestRecvQUsed(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+	defer c2.Close()
+
+	before, err := c2.RecvQUsed()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c1.Write([]byte("hello, tipc")); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := c2.RecvQUsed()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if after <= before {
+		t.Errorf("receive queue did not grow: before %d, after %d", before, after)
+	}
+}
+
+func TestListenBacklog(t *testing.T) {
+	sr := &unix.TIPCServiceRange{
+		Type:  1000,
+		Lower: 0,
+		Upper: ^uint32(0),
+	}
+
+	if _, err := ListenBacklog(unix.TIPC_CLUSTER_SCOPE, sr, 0); err == nil {
+		t.Error("expected error for zero backlog")
+	}
+
+	l, err := ListenBacklog(unix.TIPC_CLUSTER_SCOPE, sr, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Close()
+}
+
+func TestListenerPublish(t *testing.T) {
+	ranges := []*unix.TIPCServiceRange{
+		{Type: 1001, Lower: 0, Upper: 10},
+		{Type: 1002, Lower: 0, Upper: 10},
+	}
+
+	l, err := Listen(unix.TIPC_CLUSTER_SCOPE, ranges[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	if err := l.Publish(unix.TIPC_CLUSTER_SCOPE, ranges[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sr := range ranges {
+		st := &unix.SockaddrTIPC{
+			Scope: unix.TIPC_CLUSTER_SCOPE,
+			Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 5},
+		}
+
+		c, err := DialStream(st)
+		if err != nil {
+			t.Fatalf("dial type %d: %v", sr.Type, err)
+		}
+
+		ac, err := l.AcceptTIPC()
+		if err != nil {
+			t.Fatalf("accept type %d: %v", sr.Type, err)
+		}
+
+		if ac.RemoteAddr().String() != c.LocalAddr().String() {
+			t.Errorf("accepted conn from %s, want %s", ac.RemoteAddr(), c.LocalAddr())
+		}
+
+		ac.Close()
+		c.Close()
+	}
+
+	if err := l.Withdraw(unix.TIPC_CLUSTER_SCOPE, ranges[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	st := &unix.SockaddrTIPC{
+		Scope: unix.TIPC_CLUSTER_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: ranges[1].Type, Instance: 5},
+	}
+
+	if c, err := DialStream(st); err == nil {
+		c.Close()
+		t.Error("dial to withdrawn range succeeded")
+	}
+}
+
+func TestListenerBindings(t *testing.T) {
+	ranges := []*unix.TIPCServiceRange{
+		{Type: 1028, Lower: 0, Upper: 10},
+		{Type: 1028, Lower: 20, Upper: 30},
+		{Type: 1029, Lower: 0, Upper: 0},
+		{Type: 1029, Lower: 5, Upper: 5},
+	}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, ranges[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	for _, sr := range ranges[1:] {
+		if err := l.Publish(unix.TIPC_NODE_SCOPE, sr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(want []*unix.TIPCServiceRange) {
+		t.Helper()
+
+		bs, err := l.Bindings()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(bs) != len(want) {
+			t.Fatalf("got %d bindings, want %d", len(bs), len(want))
+		}
+
+		for i, b := range bs {
+			if b.Scope != unix.TIPC_NODE_SCOPE || *b.Range != *want[i] {
+				t.Errorf("binding %d = %d %+v, want %d %+v", i, b.Scope, *b.Range, unix.TIPC_NODE_SCOPE, *want[i])
+			}
+		}
+	}
+
+	check(ranges)
+
+	if err := l.Withdraw(unix.TIPC_NODE_SCOPE, ranges[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	check([]*unix.TIPCServiceRange{ranges[0], ranges[1], ranges[3]})
+
+	l.Close()
+
+	if _, err := l.Bindings(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Bindings after Close = %v, want net.ErrClosed", err)
+	}
+}
+
+func TestListenerScope(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1036, Lower: 0, Upper: 0}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	if got := l.Scope(); got != unix.TIPC_NODE_SCOPE {
+		t.Errorf("Scope() = %d, want %d", got, unix.TIPC_NODE_SCOPE)
+	}
+
+	if err := l.SetScope(unix.TIPC_CLUSTER_SCOPE); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := l.Scope(); got != unix.TIPC_CLUSTER_SCOPE {
+		t.Errorf("Scope() after SetScope = %d, want %d", got, unix.TIPC_CLUSTER_SCOPE)
+	}
+
+	bs, err := l.Bindings()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bs) != 1 || bs[0].Scope != unix.TIPC_CLUSTER_SCOPE || *bs[0].Range != *sr {
+		t.Errorf("bindings after SetScope = %+v", bs)
+	}
+
+	c, err := DialStream(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_CLUSTER_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	ac, err := l.AcceptTIPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ac.Close()
+}
+
+func TestDialAddr(t *testing.T) {
+	sr := &unix.TIPCServiceRange{
+		Type:  1003,
+		Lower: 0,
+		Upper: ^uint32(0),
+	}
+
+	l, err := Listen(unix.TIPC_CLUSTER_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	addr := &Addr{Sockaddr: &unix.SockaddrTIPC{
+		Scope: unix.TIPC_CLUSTER_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: 1003, Instance: 1},
+	}}
+
+	if _, err := DialAddr("tipc-bogus", addr); err == nil {
+		t.Error("expected error for unknown network")
+	}
+
+	c, err := DialAddr("tipc-stream", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Close()
+}
+
+func TestMulticast(t *testing.T) {
+	ranges := []*unix.TIPCServiceRange{
+		{Type: 1004, Lower: 0, Upper: 10},
+		{Type: 1004, Lower: 5, Upper: 15},
+	}
+
+	var servers []*Conn
+
+	for _, sr := range ranges {
+		s, err := ListenReliableDatagram(&unix.SockaddrTIPC{
+			Scope: unix.TIPC_CLUSTER_SCOPE,
+			Addr:  sr,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer s.Close()
+
+		servers = append(servers, s)
+	}
+
+	c, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	msg := []byte("multicast")
+	dst := &unix.TIPCServiceRange{Type: 1004, Lower: 7, Upper: 8}
+
+	if _, err := c.Multicast(msg, unix.TIPC_CLUSTER_SCOPE, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, s := range servers {
+		buf := make([]byte, 64)
+
+		n, _, err := s.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("server %d: %v", i, err)
+		}
+
+		if string(buf[:n]) != string(msg) {
+			t.Errorf("server %d: got %q, want %q", i, buf[:n], msg)
+		}
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	var servers []*Conn
+
+	for _, inst := range []uint32{0, 42, ^uint32(0)} {
+		s, err := ListenReliableDatagram(&unix.SockaddrTIPC{
+			Scope: unix.TIPC_CLUSTER_SCOPE,
+			Addr:  &unix.TIPCServiceRange{Type: 1032, Lower: inst, Upper: inst},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer s.Close()
+
+		servers = append(servers, s)
+	}
+
+	c, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	msg := []byte("broadcast")
+
+	if _, err := c.Broadcast(1032, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, s := range servers {
+		buf := make([]byte, 64)
+
+		if err := s.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+
+		n, _, err := s.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("server %d: %v", i, err)
+		}
+
+		if string(buf[:n]) != string(msg) {
+			t.Errorf("server %d: got %q, want %q", i, buf[:n], msg)
+		}
+	}
+}
+
+func TestMulticastReplicast(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1005, Lower: 0, Upper: 10}
+
+	s, err := ListenReliableDatagram(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_CLUSTER_SCOPE,
+		Addr:  sr,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	c, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	if err := c.SetMulticastMethod(-1); err == nil {
+		t.Error("expected error for unknown multicast method")
+	}
+
+	if err := c.SetMulticastMethod(MulticastReplicast); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := []byte("replicast")
+
+	if _, err := c.Multicast(msg, unix.TIPC_CLUSTER_SCOPE, sr); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 64)
+
+	n, _, err := s.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != string(msg) {
+		t.Errorf("got %q, want %q", buf[:n], msg)
+	}
+}
+
+func TestDroppable(t *testing.T) {
+	c, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	for _, on := range []bool{true, false} {
+		if err := c.SetSrcDroppable(on); err != nil {
+			t.Fatal(err)
+		}
+
+		if v, err := c.SrcDroppable(); err != nil {
+			t.Fatal(err)
+		} else if v != on {
+			t.Errorf("src droppable = %t, want %t", v, on)
+		}
+
+		if err := c.SetDestDroppable(on); err != nil {
+			t.Fatal(err)
+		}
+
+		if v, err := c.DestDroppable(); err != nil {
+			t.Fatal(err)
+		} else if v != on {
+			t.Errorf("dest droppable = %t, want %t", v, on)
+		}
+	}
+
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+	defer c2.Close()
+
+	if err := c1.SetSrcDroppable(true); err == nil {
+		t.Error("expected error setting src droppable on seqpacket socket")
+	}
+}
+
+func TestRejected(t *testing.T) {
+	c, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	self := c.LocalAddr().(*Addr).Sockaddr.(*unix.SockaddrTIPC).Addr.(*unix.TIPCSocketAddr)
+
+	dst := &Addr{Sockaddr: &unix.SockaddrTIPC{
+		Scope: unix.TIPC_CLUSTER_SCOPE,
+		Addr:  &unix.TIPCSocketAddr{Ref: self.Ref + 1000, Node: self.Node},
+	}}
+
+	if _, err := c.WriteTo([]byte("nobody home"), dst); err != nil {
+		t.Fatal(err)
+	}
+
+	_, _, err = c.ReadFrom(make([]byte, 64))
+
+	var rerr *RejectedError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected *RejectedError, got %v", err)
+	}
+
+	if rerr.Code != RejectNoPort {
+		t.Errorf("code = %d, want %d", rerr.Code, RejectNoPort)
+	}
+
+	if string(rerr.Returned) != "nobody home" {
+		t.Errorf("returned payload = %q, want %q", rerr.Returned, "nobody home")
+	}
+}
+
+func TestReliableDatagramBoundaries(t *testing.T) {
+	name := &unix.TIPCServiceName{Type: 1035, Instance: 1}
+
+	s, err := ListenReliableDatagram(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceRange{Type: name.Type, Lower: name.Instance, Upper: name.Instance},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	c, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	dst := &Addr{Sockaddr: &unix.SockaddrTIPC{Scope: unix.TIPC_NODE_SCOPE, Addr: name}}
+
+	sizes := []int{1, 100, 1000, 60000, 0, 66000}
+
+	for i, size := range sizes {
+		msg := bytes.Repeat([]byte{byte('a' + i)}, size)
+
+		if _, err := c.WriteTo(msg, dst); err != nil {
+			t.Fatalf("send %d bytes: %v", size, err)
+		}
+	}
+
+	buf := make([]byte, 70000)
+
+	for i, size := range sizes {
+		n, truncated, err := s.ReadMessage(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := bytes.Repeat([]byte{byte('a' + i)}, size)
+
+		if truncated || !bytes.Equal(buf[:n], want) {
+			t.Errorf("message %d: got %d bytes (truncated %v), want %d", i, n, truncated, size)
+		}
+	}
+
+	// A message larger than the buffer is truncated, and the rest of it
+	// does not show up in the next read.
+	if _, err := c.WriteTo([]byte("0123456789"), dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.WriteTo([]byte("next"), dst); err != nil {
+		t.Fatal(err)
+	}
+
+	n, truncated, err := s.ReadMessage(buf[:4])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !truncated || string(buf[:n]) != "0123" {
+		t.Errorf("got %q (truncated %v), want %q (truncated)", buf[:n], truncated, "0123")
+	}
+
+	n, truncated, err = s.ReadMessage(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if truncated || string(buf[:n]) != "next" {
+		t.Errorf("got %q (truncated %v), want %q", buf[:n], truncated, "next")
+	}
+}
+
+func TestDatagramDroppable(t *testing.T) {
+	rdm, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rdm.Close()
+
+	dgram, err := ListenDatagramAny()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dgram.Close()
+
+	if on, err := rdm.SrcDroppable(); err != nil || on {
+		t.Errorf("RDM SrcDroppable = %v, %v; want false", on, err)
+	}
+
+	if on, err := dgram.SrcDroppable(); err != nil || !on {
+		t.Errorf("DGRAM SrcDroppable = %v, %v; want true", on, err)
+	}
+}
+
+func TestMsgTIPC(t *testing.T) {
+	name := &unix.TIPCServiceName{Type: 1006, Instance: 3}
+
+	s, err := ListenReliableDatagram(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_CLUSTER_SCOPE,
+		Addr:  &unix.TIPCServiceRange{Type: name.Type, Lower: 0, Upper: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	c, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	dst := &Addr{Sockaddr: &unix.SockaddrTIPC{Scope: unix.TIPC_CLUSTER_SCOPE, Addr: name}}
+	msg := []byte("hello")
+
+	if n, _, err := c.WriteMsgTIPC(msg, nil, dst); err != nil {
+		t.Fatal(err)
+	} else if n != len(msg) {
+		t.Errorf("wrote %d bytes, want %d", n, len(msg))
+	}
+
+	p := make([]byte, 64)
+	oob := make([]byte, unix.CmsgSpace(12))
+
+	n, oobn, _, from, err := s.ReadMsgTIPC(p, oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(p[:n]) != string(msg) {
+		t.Errorf("got %q, want %q", p[:n], msg)
+	}
+
+	if from == nil {
+		t.Error("missing source address")
+	}
+
+	cmsgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cmsgs) != 1 || cmsgs[0].Header.Type != unix.TIPC_DESTNAME {
+		t.Fatalf("expected a single TIPC_DESTNAME control message, got %+v", cmsgs)
+	}
+
+	if typ := nativeEndian.Uint32(cmsgs[0].Data); typ != name.Type {
+		t.Errorf("destination type = %d, want %d", typ, name.Type)
+	}
+}
+
+func TestFile(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+
+	f, err := c1.File()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	c1.Close()
+
+	if _, err := f.Write([]byte("still open")); err != nil {
+		t.Fatalf("write on file after closing conn: %v", err)
+	}
+
+	buf := make([]byte, 64)
+
+	n, err := c2.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "still open" {
+		t.Errorf("got %q", buf[:n])
+	}
+}
+
+func TestCloseWrite(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+	defer c2.Close()
+
+	if err := c1.CloseWrite(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c2.Read(make([]byte, 64)); err != io.EOF {
+		t.Errorf("read after peer CloseWrite: got %v, want %v", err, io.EOF)
+	}
+
+	c1.Close()
+
+	if err := c1.CloseWrite(); err == nil {
+		t.Error("expected error from CloseWrite on closed conn")
+	}
+}
+
+func TestConcurrentClose(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+
+	var wg sync.WaitGroup
+
+	errs := make([]error, 8)
+
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = c1.Close()
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != errs[0] {
+			t.Errorf("close %d returned %v, first close returned %v", i, err, errs[0])
+		}
+	}
+}
+
+func TestNodeAddr(t *testing.T) {
+	node, err := NodeAddr()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ListenReliableDatagram(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceRange{Type: 1046, Lower: 0, Upper: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	// The topology server reports the publishing socket from the name
+	// table, independently of getsockname.
+	top, err := DialSequentialPacket(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: unix.TIPC_TOP_SRV, Instance: unix.TIPC_TOP_SRV},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer top.Close()
+
+	sub := unix.TIPCSubscr{
+		Seq:     unix.TIPCServiceRange{Type: 1046, Lower: 0, Upper: 0},
+		Timeout: 1000,
+		Filter:  unix.TIPC_SUB_PORTS,
+	}
+
+	if err := binary.Write(top, binary.BigEndian, &sub); err != nil {
+		t.Fatal(err)
+	}
+
+	var ev unix.TIPCEvent
+	if err := binary.Read(top, binary.BigEndian, &ev); err != nil {
+		t.Fatal(err)
+	}
+
+	if ev.Event != unix.TIPC_PUBLISHED {
+		t.Fatalf("{1046,0} is not published: event %d", ev.Event)
+	}
+
+	if ev.Port.Node != node {
+		t.Errorf("node = %x, want %x from the name table", node, ev.Port.Node)
+	}
+}
+
+func TestBufferSizes(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+	defer c2.Close()
+
+	const size = 64 * 1024
+
+	if err := c1.SetReadBuffer(size); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c1.SetWriteBuffer(size); err != nil {
+		t.Fatal(err)
+	}
+
+	// The kernel doubles the requested size to allow for bookkeeping
+	// overhead.
+	for _, opt := range []int{unix.SO_RCVBUF, unix.SO_SNDBUF} {
+		v, err := c1.getsockoptInt(unix.SOL_SOCKET, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if v != 2*size {
+			t.Errorf("option %d = %d, want %d", opt, v, 2*size)
+		}
+	}
+}
+
+func TestAnycast(t *testing.T) {
+	node, err := NodeAddr()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var servers []*Conn
+
+	for i := 0; i < 2; i++ {
+		s, err := ListenReliableDatagram(&unix.SockaddrTIPC{
+			Scope: unix.TIPC_NODE_SCOPE,
+			Addr:  &unix.TIPCServiceRange{Type: 1007, Lower: 1, Upper: 1},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer s.Close()
+
+		servers = append(servers, s)
+	}
+
+	c, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	if _, err := c.Anycast([]byte("anycast"), 1007, 1, node); err != nil {
+		t.Fatal(err)
+	}
+
+	received := 0
+
+	for _, s := range servers {
+		s.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+
+		if _, _, err := s.ReadFrom(make([]byte, 64)); err == nil {
+			received++
+		}
+	}
+
+	if received != 1 {
+		t.Errorf("anycast reached %d servers, want 1", received)
+	}
+}
+
+func TestListenConfigControl(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1008, Lower: 0, Upper: 10}
+
+	var (
+		called  bool
+		network string
+		address string
+	)
+
+	lc := &ListenConfig{
+		Control: func(n, a string, c syscall.RawConn) error {
+			called = true
+			network, address = n, a
+
+			var serr error
+			err := c.Control(func(fd uintptr) {
+				serr = unix.SetsockoptInt(int(fd), unix.SOL_TIPC, unix.TIPC_IMPORTANCE, unix.TIPC_HIGH_IMPORTANCE)
+			})
+			if err != nil {
+				return err
+			}
+
+			return serr
+		},
+	}
+
+	l, err := lc.Listen(unix.TIPC_CLUSTER_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	if !called {
+		t.Fatal("Control was not called")
+	}
+
+	if network != "tipc-stream" || address != "{1008,0,10}" {
+		t.Errorf("Control got (%q, %q)", network, address)
+	}
+
+	imp, err := l.conn.getsockoptInt(unix.SOL_TIPC, unix.TIPC_IMPORTANCE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if imp != unix.TIPC_HIGH_IMPORTANCE {
+		t.Errorf("importance = %d, want %d", imp, unix.TIPC_HIGH_IMPORTANCE)
+	}
+}
+
+func TestWriteBuffers(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+	defer c2.Close()
+
+	bufs := [][]byte{[]byte("header:"), nil, []byte("payload"), []byte("!")}
+
+	n, err := c1.WriteBuffers(bufs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 15 {
+		t.Errorf("wrote %d bytes, want 15", n)
+	}
+
+	buf := make([]byte, 64)
+
+	n, err = c2.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "header:payload!" {
+		t.Errorf("got %q", buf[:n])
+	}
+}
+
+func TestConsumeIOVs(t *testing.T) {
+	iovs := [][]byte{[]byte("ab"), []byte("cde"), []byte("f")}
+
+	iovs = consumeIOVs(iovs, 3)
+	if len(iovs) != 2 || string(iovs[0]) != "de" {
+		t.Fatalf("after consuming 3 bytes: %q", iovs)
+	}
+
+	iovs = consumeIOVs(iovs, 3)
+	if len(iovs) != 0 {
+		t.Fatalf("after consuming all bytes: %q", iovs)
+	}
+}
+
+func TestServer(t *testing.T) {
+	release := make(chan struct{})
+
+	srv := &Server{
+		ServiceRange: &unix.TIPCServiceRange{Type: 1009, Lower: 0, Upper: 10},
+		Handler: func(c *Conn) {
+			io.Copy(c, c)
+			<-release
+		},
+	}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.Serve() }()
+
+	st := &unix.SockaddrTIPC{
+		Scope: unix.TIPC_CLUSTER_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: 1009, Instance: 1},
+	}
+
+	var (
+		c   *Conn
+		err error
+	)
+
+	// Serve binds the service asynchronously.
+	for i := 0; i < 50; i++ {
+		if c, err = DialStream(st); err == nil {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	if _, err := c.Write([]byte("echo")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(c, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf) != "echo" {
+		t.Errorf("got %q, want %q", buf, "echo")
+	}
+
+	// The handler is blocked in io.Copy, so Shutdown must force-close it.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	close(release)
+
+	if err := srv.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if err := <-serveErr; err != ErrServerClosed {
+		t.Errorf("Serve returned %v, want %v", err, ErrServerClosed)
+	}
+
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown returned %v", err)
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	nc1, nc2, stop, err := pipemaker()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer stop()
+
+	c1, c2 := nc1.(*Conn), nc2.(*Conn)
+
+	if err := c1.SetKeepAlivePeriod(0); err == nil {
+		t.Error("expected error for zero keepalive period")
+	}
+
+	if err := c1.SetKeepAlivePeriod(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c1.SetKeepAlive(true); err != nil {
+		t.Fatal(err)
+	}
+
+	// Probes must not be visible to the peer.
+	c2.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+
+	if n, err := c2.Read(make([]byte, 64)); !isTimeout(err) {
+		t.Errorf("peer read returned %d, %v; want timeout", n, err)
+	}
+
+	if err := c1.SetKeepAlive(false); err != nil {
+		t.Fatal(err)
+	}
+
+	s1, s2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s1.Close()
+	defer s2.Close()
+
+	if err := s1.SetKeepAlive(true); err == nil {
+		t.Error("expected error enabling keepalive on seqpacket socket")
+	}
+}
+
+func isTimeout(err error) bool {
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
+
+func errInfo(code uint32) []byte {
+	oob := make([]byte, unix.CmsgSpace(8))
+
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&oob[0]))
+	h.Level = unix.SOL_TIPC
+	h.Type = unix.TIPC_ERRINFO
+	h.SetLen(unix.CmsgLen(8))
+
+	nativeEndian.PutUint32(oob[unix.CmsgLen(0):], code)
+
+	return oob
+}
+
+func TestCloseError(t *testing.T) {
+	tests := []struct {
+		oob  []byte
+		want error
+	}{
+		{nil, io.EOF},
+		{errInfo(unix.TIPC_CONN_SHUTDOWN), io.EOF},
+		{errInfo(unix.TIPC_ERR_NO_PORT), io.EOF},
+		{errInfo(unix.TIPC_ERR_NO_NODE), ErrConnAbort},
+		{errInfo(unix.TIPC_ERR_OVERLOAD), ErrConnAbort},
+	}
+
+	for _, tt := range tests {
+		if got := closeError(tt.oob); got != tt.want {
+			t.Errorf("closeError(%v) = %v, want %v", tt.oob, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejectionReturned(t *testing.T) {
+	payload := []byte("returned payload")
+
+	oob := errInfo(unix.TIPC_ERR_NO_NAME)
+	nativeEndian.PutUint32(oob[unix.CmsgLen(4):], uint32(len(payload)))
+
+	ret := make([]byte, unix.CmsgSpace(len(payload)))
+
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&ret[0]))
+	h.Level = unix.SOL_TIPC
+	h.Type = unix.TIPC_RETDATA
+	h.SetLen(unix.CmsgLen(len(payload)))
+
+	copy(ret[unix.CmsgLen(0):], payload)
+
+	oob = append(oob, ret...)
+
+	rerr := parseRejection(oob)
+	if rerr == nil {
+		t.Fatal("no rejection parsed")
+	}
+
+	if rerr.Code != RejectNoName {
+		t.Errorf("code = %d, want %d", rerr.Code, RejectNoName)
+	}
+
+	// The payload must survive reuse of the control buffer.
+	for i := range oob {
+		oob[i] = 0
+	}
+
+	if !bytes.Equal(rerr.Returned, payload) {
+		t.Errorf("returned payload = %q, want %q", rerr.Returned, payload)
+	}
+}
+
+func TestReadAfterPeerClose(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+
+	c1.Close()
+
+	if _, err := c2.Read(make([]byte, 64)); err != io.EOF {
+		t.Errorf("read after peer close: got %v, want %v", err, io.EOF)
+	}
+}
+
+func TestRawReadErrors(t *testing.T) {
+	buf := make([]byte, 64)
+
+	// A peer that closes without shutting down is a reset.
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+
+	c2.SetRawReadErrors(true)
+	c1.Close()
+
+	_, err = c2.Read(buf)
+	if !errors.Is(err, unix.ECONNRESET) {
+		t.Errorf("read after peer close: got %v, want ECONNRESET", err)
+	}
+
+	var oerr *net.OpError
+	if !errors.As(err, &oerr) {
+		t.Errorf("error %v is not a *net.OpError", err)
+	}
+
+	// Turning the option off again restores io.EOF.
+	c5, c6, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c6.Close()
+
+	c6.SetRawReadErrors(true)
+	c6.SetRawReadErrors(false)
+	c5.Close()
+
+	if _, err := c6.Read(buf); err != io.EOF {
+		t.Errorf("read with raw errors off: got %v, want %v", err, io.EOF)
+	}
+
+	// A peer that shuts down first ends the stream cleanly either way.
+	c3, c4, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c4.Close()
+
+	c4.SetRawReadErrors(true)
+	c3.CloseWrite()
+	c3.Close()
+
+	if _, err := c4.Read(buf); err != io.EOF {
+		t.Errorf("read after peer shutdown: got %v, want %v", err, io.EOF)
+	}
+}
+
+func TestPacketConn(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1010, Lower: 0, Upper: 10}
+
+	s, err := ListenDatagram(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_CLUSTER_SCOPE,
+		Addr:  sr,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var server, client net.PacketConn = NewPacketConn(s), NewPacketConn(c)
+
+	defer server.Close()
+	defer client.Close()
+
+	dst := &Addr{Sockaddr: &unix.SockaddrTIPC{
+		Scope: unix.TIPC_CLUSTER_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 1},
+	}}
+
+	if _, err := client.WriteTo([]byte("x"), nil); err == nil {
+		t.Error("expected error writing to nil address")
+	}
+
+	msg := []byte("ping")
+
+	if n, err := client.WriteTo(msg, dst); err != nil {
+		t.Fatal(err)
+	} else if n != len(msg) {
+		t.Errorf("wrote %d bytes, want %d", n, len(msg))
+	}
+
+	buf := make([]byte, 64)
+
+	n, from, err := server.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "ping" {
+		t.Errorf("got %q", buf[:n])
+	}
+
+	if from.String() != client.LocalAddr().String() {
+		t.Errorf("message from %s, want %s", from, client.LocalAddr())
+	}
+
+	server.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+
+	if _, _, err := server.ReadFrom(buf); !isTimeout(err) {
+		t.Errorf("expected timeout, got %v", err)
+	}
+}
+
+func TestListenerServiceRange(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1013, Lower: 5, Upper: 50}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	got, scope := l.ServiceRange()
+	if *got != *sr || scope != unix.TIPC_NODE_SCOPE {
+		t.Errorf("ServiceRange() = %+v, %d; want %+v, %d", got, scope, sr, unix.TIPC_NODE_SCOPE)
+	}
+
+	if s := l.Addr().String(); s != "type=1013,lower=5,upper=50,scope=node" {
+		t.Errorf("Addr() = %q", s)
+	}
+}
+
+func TestListenRestart(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1014, Lower: 0, Upper: 10}
+
+	old, err := Listen(unix.TIPC_CLUSTER_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second listener may bind the same range while the first is open.
+	l, err := Listen(unix.TIPC_CLUSTER_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Close()
+	old.Close()
+
+	l, err = Listen(unix.TIPC_CLUSTER_SCOPE, sr)
+	if err != nil {
+		t.Fatalf("re-listen after close: %v", err)
+	}
+
+	l.Close()
+}
+
+func TestConnectedService(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1015, Lower: 0, Upper: 10}
+
+	l, err := ListenSequentialPacket(unix.TIPC_CLUSTER_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	for _, instance := range []uint32{2, 7} {
+		// An unconnected seqpacket socket connects implicitly when it
+		// sends to a service name.
+		c, err := newPacketConn(unix.SOCK_SEQPACKET, nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dst := &Addr{Sockaddr: &unix.SockaddrTIPC{
+			Scope: unix.TIPC_CLUSTER_SCOPE,
+			Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: instance},
+		}}
+
+		if _, _, err := c.WriteMsgTIPC([]byte("hello"), nil, dst); err != nil {
+			t.Fatal(err)
+		}
+
+		ac, err := l.AcceptTIPC()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		name, err := ac.ConnectedService()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if name.Type != sr.Type || name.Instance != instance {
+			t.Errorf("connected to %+v, want {%d %d}", name, sr.Type, instance)
+		}
+
+		ac.Close()
+		c.Close()
+	}
+
+	c, err := DialSequentialPacket(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_CLUSTER_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	ac, err := l.AcceptTIPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ac.Close()
+
+	// A dialed connection carries the name with its data, not its setup.
+	if _, err := ac.ConnectedService(); err != ErrNotAvailable {
+		t.Errorf("before data: got %v, want %v", err, ErrNotAvailable)
+	}
+
+	if _, err := c.Write([]byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+
+	want := unix.TIPCServiceName{Type: sr.Type, Instance: 1}
+
+	// Wait for the message to arrive, without reading it.
+	for i := 0; ; i++ {
+		name, err := ac.ConnectedService()
+		if err == nil {
+			if *name != want {
+				t.Errorf("accepted side connected to %+v, want %+v", *name, want)
+			}
+
+			break
+		}
+
+		if err != ErrNotAvailable || i == 100 {
+			t.Fatalf("after data: %v", err)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	buf := make([]byte, 8)
+	if n, err := ac.Read(buf); err != nil || string(buf[:n]) != "hi" {
+		t.Fatalf("read %q, %v after ConnectedService, want hi", buf[:n], err)
+	}
+
+	// The connecting side learns the name from the first reply it reads.
+	if _, err := ac.Write([]byte("ok")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if name, err := c.ConnectedService(); err != nil || *name != want {
+		t.Errorf("connecting side: got %+v, %v, want %+v", name, err, want)
+	}
+}
+
+func TestPeerNodeID(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+	defer c1.Close()
+
+	id, err := c1.PeerNodeID()
+	if err == ErrNotAvailable {
+		t.Skip("node identity not supported")
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id == (NodeID{}) {
+		t.Fatal("got zero node identity")
+	}
+
+	id2, err := c2.PeerNodeID()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if id != id2 {
+		t.Errorf("peer identities differ: %v, %v", id, id2)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+	defer c2.Close()
+
+	if _, err := c1.Write([]byte("v2 hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	peek := make([]byte, 2)
+
+	n, err := c2.Peek(peek)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(peek[:n]) != "v2" {
+		t.Errorf("peeked %q, want %q", peek[:n], "v2")
+	}
+
+	buf := make([]byte, 64)
+
+	n, err = c2.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "v2 hello" {
+		t.Errorf("read %q after peek", buf[:n])
+	}
+
+	c2.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+
+	if _, err := c2.Peek(peek); !isTimeout(err) {
+		t.Errorf("expected timeout, got %v", err)
+	}
+}
+
+func TestUnavailable(t *testing.T) {
+	c, err := ReliableDatagram()
+	if err == nil {
+		c.Close()
+		t.Skip("TIPC is available")
+	}
+
+	if !errors.Is(err, ErrTIPCUnavailable) {
+		t.Errorf("got %v, want %v", err, ErrTIPCUnavailable)
+	}
+
+	if !errors.Is(err, unix.EAFNOSUPPORT) && !errors.Is(err, unix.EPROTONOSUPPORT) {
+		t.Errorf("error %v does not wrap the errno", err)
+	}
+
+	if _, _, err := SocketPair(); !errors.Is(err, ErrTIPCUnavailable) {
+		t.Errorf("SocketPair: got %v, want %v", err, ErrTIPCUnavailable)
+	}
+}
+
+func TestSetLinger(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+	defer c2.Close()
+
+	tests := []struct {
+		sec   int
+		onoff int32
+		secs  int32
+	}{
+		{-1, 0, 0},
+		{0, 1, 0},
+		{5, 1, 5},
+	}
+
+	for _, tt := range tests {
+		if err := c1.SetLinger(tt.sec); err != nil {
+			t.Fatal(err)
+		}
+
+		var (
+			l    *unix.Linger
+			gerr error
+		)
+
+		c1.sc.Control(func(fd uintptr) {
+			l, gerr = unix.GetsockoptLinger(int(fd), unix.SOL_SOCKET, unix.SO_LINGER)
+		})
+
+		if gerr != nil {
+			t.Fatal(gerr)
+		}
+
+		if l.Onoff != tt.onoff || (tt.onoff != 0 && l.Linger != tt.secs) {
+			t.Errorf("SetLinger(%d): got %+v", tt.sec, l)
+		}
+	}
+}
+
+var _ syscall.Conn = (*Conn)(nil)
+
+func TestSyscallConn(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+	defer c2.Close()
+
+	rc, err := c1.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var typ int
+
+	if err := rc.Control(func(fd uintptr) {
+		typ, err = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_TYPE)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if typ != unix.SOCK_SEQPACKET {
+		t.Errorf("socket type = %d, want %d", typ, unix.SOCK_SEQPACKET)
+	}
+}
+
+func TestReadMessageTruncated(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+	defer c2.Close()
+
+	msgs := []string{"a long message that will not fit", "short"}
+
+	for _, m := range msgs {
+		if _, err := c1.Write([]byte(m)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := make([]byte, 8)
+
+	n, truncated, err := c2.ReadMessage(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !truncated || string(buf[:n]) != msgs[0][:8] {
+		t.Errorf("got %q, truncated %t; want %q, true", buf[:n], truncated, msgs[0][:8])
+	}
+
+	// The rest of the first message is discarded.
+	n, truncated, err = c2.ReadMessage(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if truncated || string(buf[:n]) != msgs[1] {
+		t.Errorf("got %q, truncated %t; want %q, false", buf[:n], truncated, msgs[1])
+	}
+}
+
+func TestReadMessagePeerClose(t *testing.T) {
+	l, err := ListenSequentialPacket(unix.TIPC_NODE_SCOPE, &unix.TIPCServiceRange{Type: 1045, Lower: 0, Upper: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	c, err := DialSequentialPacket(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: 1045, Instance: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	ac, err := l.AcceptTIPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ac.Write([]byte("bye")); err != nil {
+		t.Fatal(err)
+	}
+
+	ac.Close()
+
+	buf := make([]byte, 16)
+
+	n, truncated, err := c.ReadMessage(buf)
+	if err != nil || truncated || string(buf[:n]) != "bye" {
+		t.Fatalf("ReadMessage = %q, %t, %v, want bye", buf[:n], truncated, err)
+	}
+
+	if _, _, err := c.ReadMessage(buf); err != io.EOF {
+		t.Errorf("ReadMessage after peer close: got %v, want %v", err, io.EOF)
+	}
+}
+
+func TestGroupLoopback(t *testing.T) {
+	c, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	if err := c.SetGroupLoopback(true); err == nil {
+		t.Error("expected error setting loopback before joining a group")
+	}
+
+	if err := c.JoinGroup(1016, 1, unix.TIPC_NODE_SCOPE, GroupLoopback); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 64)
+
+	// A group broadcast is a send without a destination.
+	if _, _, err := c.WriteMsgTIPC([]byte("loop"), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	c.SetReadDeadline(time.Now().Add(time.Second))
+
+	n, _, err := c.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "loop" {
+		t.Errorf("got %q, want %q", buf[:n], "loop")
+	}
+
+	if err := c.SetGroupLoopback(false); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := c.WriteMsgTIPC([]byte("no loop"), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	c.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+
+	if n, _, err := c.ReadFrom(buf); !isTimeout(err) {
+		t.Errorf("received %q, %v with loopback disabled", buf[:n], err)
+	}
+
+	if err := c.LeaveGroup(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// recvGroup receives the next message or event on gc, failing the test if
+// none arrives within a second.
+func recvGroup(t *testing.T, gc *GroupConn) (string, GroupMember, GroupEvent) {
+	t.Helper()
+
+	buf := make([]byte, 64)
+
+	gc.SetReadDeadline(time.Now().Add(time.Second))
+
+	n, from, ev, err := gc.Recv(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(buf[:n]), from, ev
+}
+
+func TestGroupConn(t *testing.T) {
+	const typ = 1037
+
+	// m1 joins as instance 1, m2 and m3 both as instance 2.
+	var members [3]*GroupConn
+
+	for i, inst := range []uint32{1, 2, 2} {
+		gc, err := ListenGroup(typ, inst, unix.TIPC_NODE_SCOPE, GroupMemberEvents)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer gc.Close()
+
+		members[i] = gc
+	}
+
+	m1, m2, m3 := members[0], members[1], members[2]
+
+	// Every member learns of the two others, whether they joined before
+	// or after it.
+	for i, gc := range members {
+		for j := 0; j < 2; j++ {
+			if _, from, ev := recvGroup(t, gc); ev != GroupMemberJoined || from.Addr == nil {
+				t.Fatalf("member %d: got %v from %+v, want member joined", i+1, ev, from)
+			}
+		}
+	}
+
+	// Send reaches the single member with the instance.
+	if _, err := m3.Send([]byte("anycast"), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, from, ev := recvGroup(t, m1)
+	if ev != GroupMessage || msg != "anycast" {
+		t.Errorf("got %v %q, want anycast message", ev, msg)
+	}
+
+	if from.Instance != 2 || from.Addr.String() != m3.LocalAddr().String() {
+		t.Errorf("anycast sender = %v instance %d, want %v instance 2", from.Addr, from.Instance, m3.LocalAddr())
+	}
+
+	// SendTo reaches a member by port identity, such as a reply.
+	if _, err := m1.SendTo([]byte("reply"), from.Addr); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg, from, _ := recvGroup(t, m3); msg != "reply" || from.Instance != 1 {
+		t.Errorf("got %q from instance %d, want reply from instance 1", msg, from.Instance)
+	}
+
+	// Multicast reaches every member with the instance, and no others.
+	if _, err := m1.Multicast([]byte("multicast"), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, gc := range []*GroupConn{m2, m3} {
+		if msg, from, _ := recvGroup(t, gc); msg != "multicast" || from.Instance != 1 {
+			t.Errorf("got %q from instance %d, want multicast from instance 1", msg, from.Instance)
+		}
+	}
+
+	// Broadcast reaches every other member.
+	if _, err := m2.Broadcast([]byte("broadcast")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, gc := range []*GroupConn{m1, m3} {
+		if msg, _, _ := recvGroup(t, gc); msg != "broadcast" {
+			t.Errorf("got %q, want broadcast", msg)
+		}
+	}
+
+	// Neither the multicast nor the broadcast went anywhere else.
+	buf := make([]byte, 64)
+
+	for _, gc := range []*GroupConn{m1, m2} {
+		gc.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+
+		if n, from, ev, err := gc.Recv(buf); !isTimeout(err) {
+			t.Errorf("unexpected %v %q from %v: %v", ev, buf[:n], from.Addr, err)
+		}
+	}
+
+	// Closing a member is reported to the others as it leaving.
+	addr := m3.LocalAddr().String()
+	m3.Close()
+
+	for _, gc := range []*GroupConn{m1, m2} {
+		_, from, ev := recvGroup(t, gc)
+		if ev != GroupMemberLeft || from.Addr.String() != addr || from.Instance != 2 {
+			t.Errorf("got %v from %v instance %d, want %s leaving", ev, from.Addr, from.Instance, addr)
+		}
+	}
+}
+
+func TestListenDatagramAny(t *testing.T) {
+	c1, err := ListenDatagramAny()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+
+	c2, err := ListenDatagramAny()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+
+	addr := c1.LocalAddr()
+	if _, ok := addr.(*Addr).PortRef(); !ok {
+		t.Fatalf("LocalAddr %v is not a port identity", addr)
+	}
+
+	if _, err := c2.WriteTo([]byte("reply here"), addr); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 64)
+
+	n, from, err := c1.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "reply here" {
+		t.Errorf("got %q", buf[:n])
+	}
+
+	if from.String() != c2.LocalAddr().String() {
+		t.Errorf("message from %s, want %s", from, c2.LocalAddr())
+	}
+}
+
+func TestListenerDeadline(t *testing.T) {
+	l, err := Listen(unix.TIPC_NODE_SCOPE, &unix.TIPCServiceRange{Type: 1017, Lower: 0, Upper: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	if err := l.SetDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	if _, err := l.Accept(); !isTimeout(err) {
+		t.Fatalf("Accept() = %v, want timeout", err)
+	}
+
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("Accept took %v", d)
+	}
+
+	if err := l.SetDeadline(time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportance(t *testing.T) {
+	c1, err := ListenDatagramAny()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+
+	c2, err := ListenDatagramAny()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+
+	if err := c2.SetImportance(unix.TIPC_CRITICAL_IMPORTANCE + 1); err == nil {
+		t.Error("invalid importance accepted")
+	}
+
+	if err := c2.SetImportance(unix.TIPC_CRITICAL_IMPORTANCE); err != nil {
+		t.Fatal(err)
+	}
+
+	if imp, err := c2.Importance(); err != nil || imp != unix.TIPC_CRITICAL_IMPORTANCE {
+		t.Errorf("Importance() = %d, %v, want %d", imp, err, unix.TIPC_CRITICAL_IMPORTANCE)
+	}
+
+	if _, err := c2.WriteTo([]byte("critical"), c1.LocalAddr()); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 64)
+
+	n, _, err := c1.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "critical" {
+		t.Errorf("got %q", buf[:n])
+	}
+}
+
+func TestConnNetwork(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1018, Lower: 0, Upper: 0}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	if n := l.Addr().Network(); n != "tipc-stream" {
+		t.Errorf("listener network = %q", n)
+	}
+
+	st := &unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	}
+
+	c, err := DialStream(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	s, err := l.AcceptTIPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	for _, a := range []net.Addr{c.LocalAddr(), c.RemoteAddr(), s.RemoteAddr()} {
+		if n := a.Network(); n != "tipc-stream" {
+			t.Errorf("%v network = %q, want tipc-stream", a, n)
+		}
+	}
+
+	sp1, sp2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sp1.Close()
+	defer sp2.Close()
+
+	if n := sp1.LocalAddr().Network(); n != "tipc-seqpacket" {
+		t.Errorf("seqpacket network = %q", n)
+	}
+
+	dgram, err := ListenDatagramAny()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dgram.Close()
+
+	if n := dgram.LocalAddr().Network(); n != "tipc-dgram" {
+		t.Errorf("dgram network = %q", n)
+	}
+
+	rdm, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rdm.Close()
+
+	if n := rdm.LocalAddr().Network(); n != "tipc-rdm" {
+		t.Errorf("rdm network = %q", n)
+	}
+}
+
+func TestListenerIncoming(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1019, Lower: 0, Upper: 0}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	incoming := l.Incoming(ctx)
+
+	const clients = 5
+
+	st := &unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	}
+
+	for i := 0; i < clients; i++ {
+		c, err := DialStream(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer c.Close()
+	}
+
+	for i := 0; i < clients; i++ {
+		select {
+		case r := <-incoming:
+			if r.Err != nil {
+				t.Fatal(r.Err)
+			}
+
+			r.Conn.Close()
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for connection %d", i)
+		}
+	}
+
+	cancel()
+
+	select {
+	case r, ok := <-incoming:
+		if ok {
+			t.Fatalf("unexpected result after cancel: %+v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+
+	// The listener is usable again once the loop has stopped.
+	c, err := DialStream(st)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	s, err := l.AcceptTIPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Close()
+}
+
+func TestIsTemporary(t *testing.T) {
+	if !isTemporary(unix.EMFILE) {
+		t.Error("EMFILE not temporary")
+	}
+
+	if isTemporary(unix.EBADF) {
+		t.Error("EBADF temporary")
+	}
+
+	if isTemporary(&net.OpError{Op: "accept", Err: timeoutError{}}) {
+		t.Error("timeout temporary")
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+// spliceProxy writes size bytes into one socket pair, splices them across to
+// a second pair, and checks that they arrive intact at the far end.
+func spliceProxy(tb testing.TB, size int) {
+	a1, a2, err := SocketPair()
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	defer a2.Close()
+
+	b1, b2, err := SocketPair()
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	defer b2.Close()
+
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i)
+	}
+
+	go func() {
+		defer a1.Close()
+
+		for p := data; len(p) > 0; {
+			n := len(p)
+			if n > 32<<10 {
+				n = 32 << 10
+			}
+
+			if _, err := a1.Write(p[:n]); err != nil {
+				tb.Error(err)
+				return
+			}
+
+			p = p[n:]
+		}
+	}()
+
+	errc := make(chan error, 1)
+
+	go func() {
+		defer b1.Close()
+
+		n, err := b1.SpliceFrom(a2)
+		if err == nil && n != int64(size) {
+			err = fmt.Errorf("spliced %d bytes, want %d", n, size)
+		}
+
+		errc <- err
+	}()
+
+	got := make([]byte, 0, size)
+	buf := make([]byte, 64<<10)
+
+	for {
+		n, err := b2.Read(buf)
+		got = append(got, buf[:n]...)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	if err := <-errc; err != nil {
+		tb.Fatal(err)
+	}
+
+	if !bytes.Equal(got, data) {
+		tb.Fatalf("received %d bytes differing from the %d sent", len(got), len(data))
+	}
+}
+
+func TestSpliceFrom(t *testing.T) {
+	spliceProxy(t, 1<<20)
+}
+
+func TestSpliceFromFallback(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+
+	go func() {
+		defer c1.Close()
+
+		if _, err := c1.SpliceFrom(strings.NewReader("not a socket")); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	buf := make([]byte, 64)
+
+	n, err := c2.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "not a socket" {
+		t.Errorf("got %q", buf[:n])
+	}
+}
+
+func BenchmarkSpliceFrom(b *testing.B) {
+	const size = 1 << 20
+
+	b.SetBytes(size)
+
+	for i := 0; i < b.N; i++ {
+		spliceProxy(b, size)
+	}
+}
+
+func TestWriteString(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+	defer c1.Close()
+
+	var _ io.StringWriter = c1
+
+	const msg = "hello, tipc"
+
+	n, err := io.WriteString(c1, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != len(msg) {
+		t.Errorf("wrote %d bytes, want %d", n, len(msg))
+	}
+
+	buf := make([]byte, 64)
+
+	n, err = c2.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != msg {
+		t.Errorf("got %q, want %q", buf[:n], msg)
+	}
+}
+
+func benchmarkWriteString(b *testing.B, write func(c *Conn, s string) (int, error)) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	defer c1.Close()
+
+	done := make(chan struct{})
+	go func() {
+		io.Copy(ioutil.Discard, c2)
+		c2.Close()
+		close(done)
+	}()
+
+	s := strings.Repeat("x", 1024)
+
+	b.SetBytes(int64(len(s)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := write(c1, s); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.StopTimer()
+	c1.CloseWrite()
+	<-done
+}
+
+func BenchmarkWriteString(b *testing.B) {
+	benchmarkWriteString(b, (*Conn).WriteString)
+}
+
+func BenchmarkWriteStringConvert(b *testing.B) {
+	benchmarkWriteString(b, func(c *Conn, s string) (int, error) {
+		return c.Write([]byte(s))
+	})
+}
+
+func TestWriteDeadlinePartial(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1020, Lower: 0, Upper: 0}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	c, err := DialStream(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	// The peer never reads, so the write blocks once the link window and
+	// the peer's receive buffer are full.
+	s, err := l.AcceptTIPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	if err := c.SetWriteDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 64<<20)
+
+	n, err := c.Write(buf)
+	if !isTimeout(err) {
+		t.Fatalf("Write() error = %v, want timeout", err)
+	}
+
+	if n <= 0 || n >= len(buf) {
+		t.Fatalf("Write() = %d, want a partial count", n)
+	}
+}
+
+func TestDialStreamImportance(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1021, Lower: 0, Upper: 0}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	st := &unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	}
+
+	if _, err := DialStreamImportance(st, -1); err == nil {
+		t.Error("invalid importance accepted")
+	}
+
+	c, err := DialStreamImportance(st, unix.TIPC_CRITICAL_IMPORTANCE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	imp, err := c.getsockoptInt(unix.SOL_TIPC, unix.TIPC_IMPORTANCE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if imp != unix.TIPC_CRITICAL_IMPORTANCE {
+		t.Errorf("importance = %d, want %d", imp, unix.TIPC_CRITICAL_IMPORTANCE)
+	}
+}
+
+func TestAcceptedImportance(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1027, Lower: 0, Upper: 0}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	if err := l.SetAcceptedImportance(unix.TIPC_CRITICAL_IMPORTANCE + 1); err == nil {
+		t.Error("invalid importance accepted")
+	}
+
+	if err := l.SetAcceptedImportance(unix.TIPC_HIGH_IMPORTANCE); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := DialStream(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	ac, err := l.AcceptTIPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ac.Close()
+
+	imp, err := ac.getsockoptInt(unix.SOL_TIPC, unix.TIPC_IMPORTANCE)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if imp != unix.TIPC_HIGH_IMPORTANCE {
+		t.Errorf("importance = %d, want %d", imp, unix.TIPC_HIGH_IMPORTANCE)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+	defer c1.Close()
+
+	const idle = 200 * time.Millisecond
+
+	if err := c2.SetIdleTimeout(idle); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for i := 0; i < 5; i++ {
+			time.Sleep(idle / 4)
+			c1.Write([]byte("x"))
+		}
+	}()
+
+	// Five reads spanning longer than the idle timeout all succeed, as
+	// each one restarts it.
+	buf := make([]byte, 1)
+	for i := 0; i < 5; i++ {
+		if _, err := c2.Read(buf); err != nil {
+			t.Fatalf("read %d: %v", i, err)
+		}
+	}
+
+	start := time.Now()
+
+	if _, err := c2.Read(buf); !isTimeout(err) {
+		t.Fatalf("expected timeout after idle gap, got %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < idle/2 {
+		t.Errorf("idle timeout fired after %v, want about %v", elapsed, idle)
+	}
+
+	// An earlier explicit deadline takes precedence.
+	if err := c2.SetIdleTimeout(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c2.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c2.Read(buf); !isTimeout(err) {
+		t.Fatalf("expected timeout from read deadline, got %v", err)
+	}
+
+	// Turning the idle timeout off leaves only the explicit deadline.
+	if err := c2.SetReadDeadline(time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c2.SetIdleTimeout(0); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(idle)
+		c1.Write([]byte("y"))
+	}()
+
+	if _, err := c2.Read(buf); err != nil {
+		t.Fatalf("read with idle timeout off: %v", err)
+	}
+}
+
+func TestConnTrace(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1033, Lower: 0, Upper: 0}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	var (
+		started, done   bool
+		doneErr         error
+		elapsed         time.Duration
+		closed          bool
+		nread, nwritten int64
+	)
+
+	d := &Dialer{Trace: &ConnTrace{
+		ConnectStart: func(network string, addr *Addr) {
+			started = true
+
+			if network != "tipc-stream" {
+				t.Errorf("ConnectStart network = %q", network)
+			}
+
+			if typ, _ := addr.ServiceType(); typ != sr.Type {
+				t.Errorf("ConnectStart addr = %v", addr)
+			}
+		},
+		ConnectDone: func(network string, addr *Addr, d time.Duration, err error) {
+			done, elapsed, doneErr = true, d, err
+		},
+		Closed: func(read, written int64) {
+			closed, nread, nwritten = true, read, written
+		},
+	}}
+
+	c, err := d.DialStream(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !started || !done {
+		t.Fatalf("connect hooks not called: start %v, done %v", started, done)
+	}
+
+	if doneErr != nil || elapsed <= 0 || elapsed > 10*time.Second {
+		t.Errorf("ConnectDone got (%v, %v)", elapsed, doneErr)
+	}
+
+	ac, err := l.AcceptTIPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ac.Close()
+
+	if _, err := c.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 16)
+	if _, err := io.ReadFull(ac, buf[:5]); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ac.Write([]byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.ReadFull(c, buf[:2]); err != nil {
+		t.Fatal(err)
+	}
+
+	if closed {
+		t.Fatal("Closed called before Close")
+	}
+
+	c.Close()
+	c.Close()
+
+	if !closed || nread != 2 || nwritten != 5 {
+		t.Errorf("Closed got (%v, %d, %d), want (true, 2, 5)", closed, nread, nwritten)
+	}
+
+	// A failed dial still reports its result.
+	done, doneErr = false, nil
+
+	if _, err := d.DialStream(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type + 1000, Instance: 0},
+	}); err == nil {
+		t.Fatal("dial to unbound service succeeded")
+	}
+
+	if !done || doneErr == nil {
+		t.Errorf("ConnectDone after failed dial got (%v, %v)", done, doneErr)
+	}
+}
+
+func TestConnStats(t *testing.T) {
+	s, err := ListenReliableDatagram(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: 1038, Instance: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	c, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	msg := bytes.Repeat([]byte("x"), 100)
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.WriteTo(msg, s.LocalAddr()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := make([]byte, 256)
+
+	s.SetReadDeadline(time.Now().Add(time.Second))
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := s.ReadFrom(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := c.Stats(), (ConnStats{BytesWritten: 300, Writes: 3}); got != want {
+		t.Errorf("sender stats = %+v, want %+v", got, want)
+	}
+
+	if got, want := s.Stats(), (ConnStats{BytesRead: 300, Reads: 3}); got != want {
+		t.Errorf("receiver stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestPacketDeadlines(t *testing.T) {
+	c, err := ListenDatagram(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: 1039, Instance: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	if err := c.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+
+	_, _, err = c.ReadFrom(make([]byte, 16))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("ReadFrom on empty socket returned %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("ReadFrom returned after %v", d)
+	}
+
+	// A write deadline in the past fails the write before it is attempted,
+	// even though the socket is writable.
+	if err := c.SetWriteDeadline(time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.WriteTo([]byte("late"), c.LocalAddr()); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("WriteTo past deadline returned %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+}
+
+func TestWriteToConnection(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1040, Lower: 0, Upper: 0}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	c, err := DialStream(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	_, err = c.WriteTo([]byte("nowhere"), ServiceNameAddr(sr.Type, 0, 0, unix.TIPC_NODE_SCOPE))
+	if !errors.Is(err, ErrNotPacketConn) {
+		t.Errorf("WriteTo on stream connection returned %v, want %v", err, ErrNotPacketConn)
+	}
+
+	if _, err := c.Broadcast(sr.Type, []byte("nowhere")); !errors.Is(err, ErrNotPacketConn) {
+		t.Errorf("Broadcast on stream connection returned %v, want %v", err, ErrNotPacketConn)
+	}
+
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+	defer c2.Close()
+
+	if _, err := c1.WriteTo([]byte("nowhere"), c2.LocalAddr()); !errors.Is(err, ErrNotPacketConn) {
+		t.Errorf("WriteTo on seqpacket connection returned %v, want %v", err, ErrNotPacketConn)
+	}
+}
+
+func TestSocketType(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1041, Lower: 0, Upper: 0}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	dialed, err := DialStream(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dialed.Close()
+
+	accepted, err := l.AcceptTIPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer accepted.Close()
+
+	pair1, pair2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer pair1.Close()
+	defer pair2.Close()
+
+	rdm, err := ReliableDatagram()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rdm.Close()
+
+	dgram, err := ListenDatagramAny()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer dgram.Close()
+
+	clone, err := rdm.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer clone.Close()
+
+	for _, tt := range []struct {
+		name string
+		c    *Conn
+		want int
+	}{
+		{"dialed", dialed, unix.SOCK_STREAM},
+		{"accepted", accepted, unix.SOCK_STREAM},
+		{"socketpair", pair1, unix.SOCK_SEQPACKET},
+		{"rdm", rdm, unix.SOCK_RDM},
+		{"dgram", dgram, unix.SOCK_DGRAM},
+		{"clone", clone, unix.SOCK_RDM},
+	} {
+		if got := tt.c.SocketType(); got != tt.want {
+			t.Errorf("%s: SocketType() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListenerCloseGraceful(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1042, Lower: 0, Upper: 0}
+	dst := &unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	}
+
+	// accept returns a client and the connection accepted for it.
+	accept := func(l *Listener) (*Conn, *Conn) {
+		c, err := DialStream(dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ac, err := l.AcceptTIPC()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return c, ac
+	}
+
+	t.Run("drained", func(t *testing.T) {
+		l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c, ac := accept(l)
+		defer c.Close()
+
+		time.AfterFunc(100*time.Millisecond, func() { ac.Close() })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		start := time.Now()
+
+		if err := l.CloseGraceful(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if d := time.Since(start); d < 100*time.Millisecond {
+			t.Errorf("CloseGraceful returned after %v, before the connection was closed", d)
+		}
+
+		if _, err := l.AcceptTIPC(); !errors.Is(err, net.ErrClosed) {
+			t.Errorf("AcceptTIPC after CloseGraceful returned %v", err)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c1, ac1 := accept(l)
+		defer c1.Close()
+
+		c2, ac2 := accept(l)
+		defer c2.Close()
+
+		// The first connection finishes in time, the second never does.
+		time.AfterFunc(50*time.Millisecond, func() { ac1.Close() })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+
+		if err := l.CloseGraceful(ctx); err != context.DeadlineExceeded {
+			t.Fatalf("CloseGraceful returned %v, want %v", err, context.DeadlineExceeded)
+		}
+
+		if d := time.Since(start); d < 300*time.Millisecond {
+			t.Errorf("CloseGraceful returned after %v, before the deadline", d)
+		}
+
+		if !ac2.isClosed() {
+			t.Error("remaining connection was not closed")
+		}
+
+		c2.SetReadDeadline(time.Now().Add(time.Second))
+
+		if _, err := c2.Read(make([]byte, 1)); err != io.EOF {
+			t.Errorf("client read %v, want %v", err, io.EOF)
+		}
+	})
+}
+
+func TestBufferedWriter(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1043, Lower: 0, Upper: 0}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	c, err := DialStream(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	ac, err := l.AcceptTIPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer ac.Close()
+
+	ac.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// Without a timer, small writes stay buffered until Flush, which
+	// sends them in one write.
+	bw, err := c.BufferedWriter(1024, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err := bw.Write([]byte("0123456789")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := bw.Buffered(); n != 100 {
+		t.Errorf("Buffered() = %d, want 100", n)
+	}
+
+	if w := c.Stats().Writes; w != 0 {
+		t.Errorf("%d writes before Flush", w)
+	}
+
+	if err := bw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := c.Stats().Writes; w != 1 {
+		t.Errorf("%d writes after Flush, want 1", w)
+	}
+
+	buf := make([]byte, 100)
+	if _, err := io.ReadFull(ac, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := strings.Repeat("0123456789", 10); string(buf) != want {
+		t.Errorf("read %q, want %q", buf, want)
+	}
+
+	// With a timer, buffered data is sent without a Flush.
+	bw, err = c.BufferedWriter(1024, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bw.Write([]byte("timed")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.ReadFull(ac, buf[:5]); err != nil || string(buf[:5]) != "timed" {
+		t.Errorf("read %q, %v, want timed", buf[:5], err)
+	}
+
+	// Close sends what is still buffered before closing.
+	if _, err := bw.Write([]byte("closing")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadAll(ac)
+	if err != nil || string(b) != "closing" {
+		t.Errorf("read %q, %v, want closing", b, err)
+	}
+
+	if _, err := bw.Write([]byte("x")); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Write after Close = %v, want %v", err, net.ErrClosed)
+	}
+
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+	defer c2.Close()
+
+	if _, err := c1.BufferedWriter(0, 0); err == nil {
+		t.Error("BufferedWriter accepted a seqpacket connection")
+	}
+}
+
+func TestWriteToRetry(t *testing.T) {
+	old := retryWrite
+	defer func() { retryWrite = old }()
+
+	var (
+		calls int
+		fails int
+		fail  error
+	)
+
+	// Fail the first sends as a socket without buffer space would.
+	retryWrite = func(tc *Conn, p []byte, addr net.Addr) (int, error) {
+		calls++
+		if calls <= fails {
+			return 0, &net.OpError{Op: "write", Net: "tipc", Err: os.NewSyscallError("sendto", fail)}
+		}
+
+		return len(p), nil
+	}
+
+	tc := &Conn{}
+	addr := &Addr{Sockaddr: &unix.SockaddrTIPC{Addr: &unix.TIPCSocketAddr{Ref: 1}}}
+	policy := RetryPolicy{MaxAttempts: 4, Backoff: time.Millisecond}
+
+	calls, fails, fail = 0, 3, unix.ENOBUFS
+	n, err := tc.WriteToRetry([]byte("hello"), addr, policy)
+	if err != nil || n != 5 || calls != 4 {
+		t.Errorf("after 3 transient failures: n=%d err=%v calls=%d, want 5, nil, 4", n, err, calls)
+	}
+
+	calls, fails, fail = 0, 10, unix.ENOMEM
+	if _, err := tc.WriteToRetry([]byte("hello"), addr, policy); !errors.Is(err, unix.ENOMEM) || calls != 4 {
+		t.Errorf("exhausted: err=%v calls=%d, want ENOMEM after 4", err, calls)
+	}
+
+	calls, fails, fail = 0, 10, unix.EHOSTUNREACH
+	if _, err := tc.WriteToRetry([]byte("hello"), addr, policy); !errors.Is(err, unix.EHOSTUNREACH) || calls != 1 {
+		t.Errorf("permanent: err=%v calls=%d, want EHOSTUNREACH after 1", err, calls)
+	}
+
+	calls, fails, fail = 0, 10, unix.EAGAIN
+	if _, err := tc.WriteToRetry([]byte("hello"), addr, RetryPolicy{Backoff: time.Millisecond}); calls != 3 || err == nil {
+		t.Errorf("default attempts: err=%v calls=%d, want an error after 3", err, calls)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	caps, err := Capabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Logf("capabilities: %+v", caps)
+
+	// A kernel that can run the group tests has groups.
+	gc, err := ListenGroup(1044, 1, unix.TIPC_NODE_SCOPE, 0)
+	if err != nil {
+		return
+	}
+
+	defer gc.Close()
+
+	if !caps.SupportsGroups {
+		t.Error("groups work but are not reported")
+	}
+}
+
+func TestProbeCaps(t *testing.T) {
+	probe := func(err error) capProbe {
+		return capProbe{"probe", func(fd int) error { return err }}
+	}
+
+	caps, err := probeCaps(-1, probe(nil), probe(unix.ENOTTY), probe(unix.ENOPROTOOPT))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Caps{SupportsGroups: true}
+	if caps != want {
+		t.Errorf("caps = %+v, want %+v", caps, want)
+	}
+
+	caps, err = probeCaps(-1, probe(unix.EINVAL), probe(nil), probe(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = Caps{SupportsNodeIdentity: true, SupportsReplicast: true}
+	if caps != want {
+		t.Errorf("caps = %+v, want %+v", caps, want)
+	}
+
+	if _, err := probeCaps(-1, probe(nil), probe(unix.EBADF), probe(nil)); !errors.Is(err, unix.EBADF) {
+		t.Errorf("error = %v, want EBADF", err)
+	}
+
+	// The probes themselves fail on a closed socket, rather than reporting a
+	// missing feature.
+	for _, p := range []capProbe{probeGroups, probeNodeIdentity, probeReplicast} {
+		if _, err := p.supported(-1); !errors.Is(err, unix.EBADF) {
+			t.Errorf("%s probe on a bad socket: error = %v, want EBADF", p.op, err)
+		}
+	}
+}
+
+func TestListenerFromFile(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1034, Lower: 0, Upper: 0}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := l.FileForExec()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flags, err := unix.FcntlInt(f.Fd(), unix.F_GETFD, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if flags&unix.FD_CLOEXEC != 0 {
+		t.Error("FileForExec descriptor has close-on-exec set")
+	}
+
+	l2, err := ListenerFromFile(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l2.Close()
+
+	// The rebuilt listener keeps working once the originals are gone.
+	f.Close()
+	l.Close()
+
+	c, err := DialStream(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	ac, err := l2.AcceptTIPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ac.Close()
+
+	// Files that are not listening TIPC sockets are rejected.
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+	defer c1.Close()
+
+	cf, err := c1.File()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer cf.Close()
+
+	if _, err := ListenerFromFile(cf); err == nil {
+		t.Error("ListenerFromFile accepted a connected socket")
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer r.Close()
+	defer w.Close()
+
+	if _, err := ListenerFromFile(r); err == nil {
+		t.Error("ListenerFromFile accepted a pipe")
+	}
+}
+
+func TestAbort(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+
+	// Abort skips the shutdown a drained Close would make, and a peer with
+	// raw read errors tells the two apart.
+	c1.SetDrainOnClose(true)
+	c2.SetRawReadErrors(true)
+
+	if _, err := c2.Write([]byte("never read")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c1.Abort(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c1.Abort(); err != nil {
+		t.Errorf("second Abort: %v", err)
+	}
+
+	if err := c1.Close(); err != nil {
+		t.Errorf("Close after Abort: %v", err)
+	}
+
+	if _, err := c2.Read(make([]byte, 64)); !errors.Is(err, unix.ECONNRESET) {
+		t.Errorf("read after peer abort: got %v, want ECONNRESET", err)
+	}
+}
+
+func TestReadCloseReason(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		drain bool
+		want  error
+	}{
+		{"reset", false, unix.ECONNRESET},
+		{"orderly", true, io.EOF},
+	} {
+		c1, c2, err := SocketPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c1.SetDrainOnClose(tt.drain)
+		c2.SetRawReadErrors(true)
+
+		// Reads before the close reuse the pooled control buffer.
+		buf := make([]byte, 64)
+
+		for i := 0; i < 3; i++ {
+			if _, err := c1.Write([]byte("data")); err != nil {
+				t.Fatal(err)
+			}
+
+			if n, err := c2.Read(buf); err != nil || string(buf[:n]) != "data" {
+				t.Fatalf("%s: read %q, %v", tt.name, buf[:n], err)
+			}
+		}
+
+		// Leave a message unread on the closing side.
+		if _, err := c2.Write([]byte("never read")); err != nil {
+			t.Fatal(err)
+		}
+
+		c1.Close()
+
+		if _, err := c2.Read(buf); !errors.Is(err, tt.want) {
+			t.Errorf("%s: read after peer close = %v, want %v", tt.name, err, tt.want)
+		}
+
+		c2.Close()
+	}
+}
+
+func TestListenPacket(t *testing.T) {
+	if _, err := ListenPacket("tipc-stream", nil); err == nil {
+		t.Error("ListenPacket accepted tipc-stream")
+	}
+
+	if _, err := ListenPacket("tipc-rdm", &Addr{Sockaddr: &unix.SockaddrInet4{}}); err == nil {
+		t.Error("ListenPacket accepted an inet address")
+	}
+
+	srv, err := ListenPacket("tipc-dgram", ServiceRangeAddr(1022, 0, 10, unix.TIPC_NODE_SCOPE))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer srv.Close()
+
+	cli, err := ListenPacket("tipc-rdm", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer cli.Close()
+
+	if _, err := cli.WriteTo([]byte("hello"), ServiceNameAddr(1022, 5, 0, unix.TIPC_NODE_SCOPE)); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 64)
+
+	n, from, err := srv.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "hello" {
+		t.Errorf("got %q", buf[:n])
+	}
+
+	if from.Network() != "tipc-dgram" {
+		t.Errorf("source network = %q", from.Network())
+	}
+}
+
+// echoServer listens on sr and echoes each accepted connection until the
+// listener is closed. It returns a function that closes the listener along
+// with every accepted connection.
+func echoServer(t *testing.T, sr *unix.TIPCServiceRange) func() {
+	t.Helper()
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu    sync.Mutex
+		conns []*Conn
+	)
+
+	go func() {
+		for {
+			c, err := l.AcceptTIPC()
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			conns = append(conns, c)
+			mu.Unlock()
+
+			go io.Copy(c, c)
+		}
+	}()
+
+	return func() {
+		l.Close()
+
+		mu.Lock()
+		for _, c := range conns {
+			c.Close()
+		}
+		mu.Unlock()
+	}
+}
+
+func TestReconnectingConn(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1023, Lower: 0, Upper: 0}
+
+	stop := echoServer(t, sr)
+
+	var (
+		mu     sync.Mutex
+		states []ConnState
+	)
+
+	rc, err := DialReconnecting(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	}, ReconnectOptions{
+		StateChange: func(s ConnState) {
+			mu.Lock()
+			states = append(states, s)
+			mu.Unlock()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rc.Close()
+
+	echo := func(msg string) error {
+		rc.SetDeadline(time.Now().Add(200 * time.Millisecond))
+
+		if _, err := rc.Write([]byte(msg)); err != nil {
+			return err
+		}
+
+		buf := make([]byte, 64)
+
+		n, err := rc.Read(buf)
+		if err != nil {
+			return err
+		}
+
+		if string(buf[:n]) != msg {
+			return fmt.Errorf("echoed %q, want %q", buf[:n], msg)
+		}
+
+		return nil
+	}
+
+	if err := echo("before"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Kill the server, then bring it back.
+	stop()
+
+	time.Sleep(50 * time.Millisecond)
+
+	stop = echoServer(t, sr)
+	defer stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		err := echo("after")
+		if err == nil {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("did not recover: %v", err)
+		}
+	}
+
+	rc.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	want := []ConnState{StateReconnecting, StateConnected, StateClosed}
+	if fmt.Sprint(states) != fmt.Sprint(want) {
+		t.Errorf("states = %v, want %v", states, want)
+	}
+
+	if _, err := rc.Write([]byte("x")); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+}
+
+func TestReconnectingConnFailWrites(t *testing.T) {
+	r := &ReconnectingConn{
+		addr:  &unix.SockaddrTIPC{Addr: &unix.TIPCServiceName{Type: 1}},
+		opts:  ReconnectOptions{FailWrites: true},
+		done:  make(chan struct{}),
+		ready: make(chan struct{}),
+	}
+
+	if _, err := r.Write([]byte("x")); !errors.Is(err, ErrReconnecting) {
+		t.Errorf("Write while reconnecting = %v, want %v", err, ErrReconnecting)
+	}
+
+	r.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+
+	if _, err := r.Read(make([]byte, 1)); !isTimeout(err) || !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Read while reconnecting = %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+}
+
+func TestDrainOnClose(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 1024, Lower: 0, Upper: 0}
+
+	l, err := Listen(unix.TIPC_NODE_SCOPE, sr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	c, err := DialStream(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceName{Type: sr.Type, Instance: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := l.AcceptTIPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer s.Close()
+
+	data := bytes.Repeat([]byte("drain"), 200<<10)
+
+	go func() {
+		c.SetDrainOnClose(true)
+
+		if _, err := c.Write(data); err != nil {
+			t.Error(err)
+		}
+
+		c.Close()
+	}()
+
+	got, err := ioutil.ReadAll(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, data) {
+		t.Errorf("peer read %d bytes, want %d", len(got), len(data))
+	}
+}
+
+func TestReadBuffers(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+	defer c2.Close()
+
+	if _, err := c1.Write([]byte("HEADbody of the message")); err != nil {
+		t.Fatal(err)
+	}
+
+	head := make([]byte, 4)
+	body := make([]byte, 64)
+
+	n, err := c2.ReadBuffers([][]byte{head, body})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != len("HEADbody of the message") {
+		t.Fatalf("read %d bytes", n)
+	}
+
+	if string(head) != "HEAD" || string(body[:n-4]) != "body of the message" {
+		t.Errorf("got %q and %q", head, body[:n-4])
+	}
+
+	c1.Close()
+
+	if _, err := c2.ReadBuffers([][]byte{head}); err != io.EOF {
+		t.Errorf("read after peer close: got %v, want %v", err, io.EOF)
+	}
+}
+
+func TestClone(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+
+	clone, err := c1.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer clone.Close()
+
+	if err := c1.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := clone.Write([]byte("from clone")); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 64)
+
+	n, err := c2.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "from clone" {
+		t.Errorf("got %q", buf[:n])
+	}
+
+	if _, err := c2.Write([]byte("to clone")); err != nil {
+		t.Fatal(err)
+	}
+
+	clone.SetReadDeadline(time.Now().Add(time.Second))
+
+	n, err = clone.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "to clone" {
+		t.Errorf("got %q", buf[:n])
+	}
+}
+
+func TestSetReadLimit(t *testing.T) {
+	srv, err := ListenDatagramAny()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer srv.Close()
+
+	cli, err := ListenDatagramAny()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer cli.Close()
+
+	srv.SetReadLimit(16)
+
+	dst := srv.LocalAddr()
+
+	for _, m := range []string{"this message is longer than sixteen bytes", "short"} {
+		if _, err := cli.WriteTo([]byte(m), dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	buf := make([]byte, 1024)
+
+	_, from, err := srv.ReadFrom(buf)
+	if err != ErrMessageTooLarge {
+		t.Fatalf("oversized message: got %v, want %v", err, ErrMessageTooLarge)
+	}
+
+	if from == nil || from.String() != cli.LocalAddr().String() {
+		t.Errorf("oversized message from %v, want %v", from, cli.LocalAddr())
+	}
+
+	n, _, err := srv.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf[:n]) != "short" {
+		t.Errorf("got %q, want %q", buf[:n], "short")
+	}
+
+	// The limit also applies to reads into buffers no longer than it, and a
+	// message within the limit is still truncated to the buffer.
+	for _, m := range []string{"seventeen bytes!!", "sixteen bytes!!!"} {
+		if _, err := cli.WriteTo([]byte(m), dst); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	small := make([]byte, 8)
+
+	if _, _, err := srv.ReadFrom(small); err != ErrMessageTooLarge {
+		t.Fatalf("oversized message into a small buffer: got %v, want %v", err, ErrMessageTooLarge)
+	}
+
+	n, _, err = srv.ReadFrom(small)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(small[:n]) != "sixteen " {
+		t.Errorf("got %q, want %q", small[:n], "sixteen ")
+	}
+}
+
+func TestAcceptClosed(t *testing.T) {
+	l, err := Listen(unix.TIPC_NODE_SCOPE, &unix.TIPCServiceRange{Type: 1025, Lower: 0, Upper: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errc := make(chan error, 1)
+
+	go func() {
+		_, err := l.Accept()
+		errc <- err
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	l.Close()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, net.ErrClosed) {
+			t.Errorf("pending Accept: got %v, want %v", err, net.ErrClosed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Accept did not return after Close")
+	}
+
+	if _, err := l.Accept(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Accept after Close: got %v, want %v", err, net.ErrClosed)
+	}
+}
+
+func TestReadNonblock(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c1.Close()
+	defer c2.Close()
+
+	buf := make([]byte, 64)
+
+	_, err = c2.ReadNonblock(buf)
+	if err != ErrWouldBlock {
+		t.Fatalf("empty socket: got %v, want %v", err, ErrWouldBlock)
+	}
+
+	if !errors.Is(err, unix.EAGAIN) {
+		t.Error("ErrWouldBlock does not wrap EAGAIN")
+	}
+
+	if _, err := c1.Write([]byte("ready")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Wait for the message without going through the poller.
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		n, err := c2.ReadNonblock(buf)
+		if err == ErrWouldBlock && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+			continue
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(buf[:n]) != "ready" {
+			t.Errorf("got %q", buf[:n])
+		}
+
+		break
+	}
+}
+
+func TestDialURI(t *testing.T) {
+	l, err := Listen(unix.TIPC_NODE_SCOPE, &unix.TIPCServiceRange{Type: 1026, Lower: 0, Upper: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := DialURI(ctx, "tipc://1026.3?scope=node")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	s, err := l.AcceptTIPC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Close()
+
+	if _, err := DialURI(ctx, "tipc://1026:0-10"); err == nil {
+		t.Error("dialing a service range succeeded")
+	}
+
+	if _, err := DialURI(ctx, "bogus"); err == nil {
+		t.Error("dialing a malformed URI succeeded")
+	}
+
+	cancel()
+
+	if _, err := DialURI(ctx, "tipc://1026.3"); err != context.Canceled {
+		t.Errorf("dial with cancelled context: got %v, want %v", err, context.Canceled)
+	}
+}
+
+// readWriter joins a reader and a writer into an io.ReadWriter.
+type readWriter struct {
+	io.Reader
+	io.Writer
+}
+
+func TestFramedConn(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := NewFramedConn(&buf, FrameOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	frames := [][]byte{[]byte("first"), {}, bytes.Repeat([]byte("x"), 1000), []byte("last")}
+
+	for _, f := range frames {
+		if err := w.WriteFrame(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := buf.Bytes()[:4]; !bytes.Equal(got, []byte{0, 0, 0, 5}) {
+		t.Errorf("prefix = %x, want 00000005", got)
+	}
+
+	// Reading one byte at a time exercises frames split across reads.
+	r, err := NewFramedConn(readWriter{iotest.OneByteReader(&buf), ioutil.Discard}, FrameOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range frames {
+		got, err := r.ReadFrame()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("frame %d = %q, want %q", i, got, want)
+		}
+	}
+
+	if _, err := r.ReadFrame(); err != io.EOF {
+		t.Errorf("read past last frame: got %v, want %v", err, io.EOF)
+	}
+}
+
+func TestFramedConnErrors(t *testing.T) {
+	var buf bytes.Buffer
+
+	fc, err := NewFramedConn(&buf, FrameOptions{PrefixSize: 2, ByteOrder: binary.LittleEndian, MaxFrameSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fc.WriteFrame(make([]byte, 9)); err != ErrFrameTooLarge {
+		t.Errorf("oversize write: got %v, want %v", err, ErrFrameTooLarge)
+	}
+
+	if buf.Len() != 0 {
+		t.Errorf("oversize write wrote %d bytes", buf.Len())
+	}
+
+	if err := fc.WriteFrame([]byte("12345678")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := buf.Bytes()[:2]; !bytes.Equal(got, []byte{8, 0}) {
+		t.Errorf("prefix = %x, want 0800", got)
+	}
+
+	buf.Reset()
+	buf.Write([]byte{9, 0})
+	buf.Write(make([]byte, 9))
+
+	if _, err := fc.ReadFrame(); err != ErrFrameTooLarge {
+		t.Errorf("oversize read: got %v, want %v", err, ErrFrameTooLarge)
+	}
+
+	buf.Reset()
+	buf.Write([]byte{4, 0, 'a', 'b'})
+
+	if _, err := fc.ReadFrame(); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated frame: got %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	buf.Reset()
+	buf.Write([]byte{4})
+
+	if _, err := fc.ReadFrame(); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated prefix: got %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	if _, err := NewFramedConn(&buf, FrameOptions{PrefixSize: 3}); err == nil {
+		t.Error("prefix size 3 accepted")
+	}
+}
+
+func TestFramedConnStream(t *testing.T) {
+	c1, c2, err := SocketPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c2.Close()
+	defer c1.Close()
+
+	w, _ := NewFramedConn(c1, FrameOptions{})
+	r, _ := NewFramedConn(c2, FrameOptions{})
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			w.WriteFrame([]byte(strings.Repeat("f", i*1000)))
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		p, err := r.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(p) != i*1000 {
+			t.Errorf("frame %d has %d bytes, want %d", i, len(p), i*1000)
+		}
+	}
+}
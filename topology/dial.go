@@ -0,0 +1,49 @@
+package topology
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mischief/tipc"
+	"golang.org/x/sys/unix"
+)
+
+// DialWhenAvailable waits until the service {typ, instance} is published in
+// the cluster, then connects to it with a stream socket. It returns early with
+// ctx's error if ctx is done first.
+func DialWhenAvailable(ctx context.Context, typ, instance uint32) (*tipc.Conn, error) {
+	top, err := Topology(0)
+	if err != nil {
+		return nil, err
+	}
+
+	defer top.Close()
+
+	if err := top.SubscribeService(typ, instance, instance, 0); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	evc, errc := top.Events(ctx)
+
+	for evt := range evc {
+		if evt.Kind != Published {
+			continue
+		}
+
+		st := &unix.SockaddrTIPC{
+			Scope: unix.TIPC_CLUSTER_SCOPE,
+			Addr:  &unix.TIPCServiceName{Type: typ, Instance: instance},
+		}
+
+		return tipc.DialStream(st)
+	}
+
+	if err := <-errc; err != nil {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("topology: event stream ended")
+}
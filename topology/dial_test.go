@@ -0,0 +1,92 @@
+package topology
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mischief/tipc"
+	"golang.org/x/sys/unix"
+)
+
+func TestDialWhenAvailable(t *testing.T) {
+	sr := &unix.TIPCServiceRange{Type: 2001, Lower: 0, Upper: 10}
+
+	lc := make(chan *tipc.Listener, 1)
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+
+		l, err := tipc.Listen(unix.TIPC_CLUSTER_SCOPE, sr)
+		if err != nil {
+			t.Error(err)
+		}
+
+		lc <- l
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c, err := DialWhenAvailable(ctx, sr.Type, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Close()
+
+	if l := <-lc; l != nil {
+		l.Close()
+	}
+}
+
+func TestDialWhenAvailableTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := DialWhenAvailable(ctx, 2002, 1); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestPortServices(t *testing.T) {
+	// The client publishes its name on a socket of its own.
+	named, err := tipc.ListenReliableDatagram(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceRange{Type: 2007, Lower: 40, Upper: 49},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer named.Close()
+
+	// Another socket binding the same type must not be reported.
+	other, err := tipc.ListenReliableDatagram(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceRange{Type: 2007, Lower: 1, Upper: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer other.Close()
+
+	ref, _ := named.LocalAddr().(*tipc.Addr).PortRef()
+	node, _ := named.LocalAddr().(*tipc.Addr).Node()
+	port := unix.TIPCSocketAddr{Ref: ref, Node: node}
+
+	got, err := PortServices(port, 2007, 0, ^uint32(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := unix.TIPCServiceRange{Type: 2007, Lower: 40, Upper: 49}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("PortServices = %+v, want [%+v]", got, want)
+	}
+
+	if got, err := PortServices(port, 2008, 0, ^uint32(0)); err != nil || len(got) != 0 {
+		t.Errorf("PortServices for unbound type = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,96 @@
+package topology
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// EventKind is the kind of a topology event.
+type EventKind uint32
+
+const (
+	Published           EventKind = unix.TIPC_PUBLISHED
+	Withdrawn           EventKind = unix.TIPC_WITHDRAWN
+	SubscriptionTimeout EventKind = unix.TIPC_SUBSCR_TIMEOUT
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case Published:
+		return "published"
+	case Withdrawn:
+		return "withdrawn"
+	case SubscriptionTimeout:
+		return "subscription timeout"
+	}
+
+	return fmt.Sprintf("EventKind(%d)", uint32(k))
+}
+
+// Event is a decoded topology event.
+type Event struct {
+	Kind EventKind
+
+	// Lower and Upper are the bounds of the publication that matched the
+	// subscription.
+	Lower uint32
+	Upper uint32
+
+	// PortRef and PortNode identify the socket that published the range.
+	PortRef  uint32
+	PortNode uint32
+
+	// Subscription is the subscription that produced the event.
+	Subscription unix.TIPCSubscr
+}
+
+// DecodeEvent converts an event returned by ReadEvent into an Event.
+func DecodeEvent(e *unix.TIPCEvent) Event {
+	return Event{
+		Kind:         EventKind(e.Event),
+		Lower:        e.Lower,
+		Upper:        e.Upper,
+		PortRef:      e.Port.Ref,
+		PortNode:     e.Port.Node,
+		Subscription: e.S,
+	}
+}
+
+func (e Event) String() string {
+	s := e.Subscription.Seq
+
+	return fmt.Sprintf("%s {%d,%d,%d} port=%d,node=%x (subscription {%d,%d,%d})",
+		e.Kind, s.Type, e.Lower, e.Upper, e.PortRef, e.PortNode, s.Type, s.Lower, s.Upper)
+}
+
+// NodeEvent reports a change in the reachability of a node.
+type NodeEvent struct {
+	Node uint32
+	Up   bool
+}
+
+func (e NodeEvent) String() string {
+	if e.Up {
+		return fmt.Sprintf("node %x up", e.Node)
+	}
+
+	return fmt.Sprintf("node %x down", e.Node)
+}
+
+// NodeEvent decodes an event for a subscription made with SubscribeNode. ok
+// is false for events of other subscriptions and for subscription timeouts.
+func (e Event) NodeEvent() (ne NodeEvent, ok bool) {
+	if e.Subscription.Seq.Type != unix.TIPC_NODE_STATE {
+		return NodeEvent{}, false
+	}
+
+	switch e.Kind {
+	case Published:
+		return NodeEvent{Node: e.Lower, Up: true}, true
+	case Withdrawn:
+		return NodeEvent{Node: e.Lower}, true
+	}
+
+	return NodeEvent{}, false
+}
@@ -0,0 +1,250 @@
+package topology
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrMuxClosed is returned by Mux.Subscribe after the Mux has been closed or
+// has stopped receiving events.
+var ErrMuxClosed = errors.New("topology: mux closed")
+
+// Mux multiplexes any number of subscriptions over a single topology
+// connection, delivering the events of each subscription on a channel of its
+// own.
+//
+// Events are matched to subscriptions through the subscription's user
+// handle, which the topology server echoes back unchanged in every event.
+// The Mux assigns a unique handle to each subscription, so subscriptions
+// that cover the same service range are still told apart. Any handle set by
+// the caller is overwritten.
+type Mux struct {
+	top    *TopologyConn
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.Mutex
+	subs    map[uint64]*muxSub
+	nextID  uint64
+	stopped bool
+	err     error
+}
+
+// NewMux starts routing the events received on top to the subscriptions made
+// through the returned Mux. The Mux takes over reading from top, which must
+// not be read from otherwise, and closes it when the Mux is closed.
+func NewMux(top *TopologyConn) *Mux {
+	m := &Mux{
+		top:  top,
+		done: make(chan struct{}),
+		subs: make(map[uint64]*muxSub),
+	}
+
+	var ctx context.Context
+	ctx, m.cancel = context.WithCancel(context.Background())
+
+	go m.run(ctx)
+
+	return m
+}
+
+// Subscription is a subscription made through a Mux.
+type Subscription struct {
+	// C delivers the events of the subscription. It is closed when the
+	// subscription times out, after the SubscriptionTimeout event, when it
+	// is cancelled, and when the Mux stops. The Mux waits for each event to
+	// be received, so C must be drained for the other subscriptions to make
+	// progress.
+	C <-chan Event
+
+	m   *Mux
+	id  uint64
+	sub unix.TIPCSubscr
+}
+
+// muxSub is the delivery state of a subscription. mu serializes delivery
+// with closing c, while done interrupts a blocked delivery.
+type muxSub struct {
+	c        chan Event
+	done     chan struct{}
+	stopOnce sync.Once
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (s *muxSub) deliver(e Event, stop <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return
+	}
+
+	select {
+	case s.c <- e:
+	case <-s.done:
+	case <-stop:
+	}
+}
+
+func (s *muxSub) close() {
+	s.stopOnce.Do(func() { close(s.done) })
+
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.c)
+	}
+	s.mu.Unlock()
+}
+
+// Subscribe sends sub to the topology server and returns a Subscription
+// delivering its events.
+func (m *Mux) Subscribe(sub unix.TIPCSubscr) (*Subscription, error) {
+	s := &muxSub{
+		c:    make(chan Event),
+		done: make(chan struct{}),
+	}
+
+	m.mu.Lock()
+
+	if m.stopped {
+		m.mu.Unlock()
+		return nil, ErrMuxClosed
+	}
+
+	m.nextID++
+	id := m.nextID
+	m.subs[id] = s
+
+	m.mu.Unlock()
+
+	putHandle(&sub, id)
+
+	if err := m.top.Subscribe(&sub); err != nil {
+		m.remove(id)
+		return nil, err
+	}
+
+	return &Subscription{C: s.c, m: m, id: id, sub: sub}, nil
+}
+
+// SubscribeService is like TopologyConn.SubscribeService, but returns a
+// Subscription delivering the events of the new subscription.
+func (m *Mux) SubscribeService(typ, lower, upper uint32, timeout time.Duration) (*Subscription, error) {
+	return m.Subscribe(*newSubscr(typ, lower, upper, timeout, unix.TIPC_SUB_SERVICE))
+}
+
+// SubscribePort is like TopologyConn.SubscribePort, but returns a
+// Subscription delivering the events of the new subscription.
+func (m *Mux) SubscribePort(typ, lower, upper uint32, timeout time.Duration) (*Subscription, error) {
+	return m.Subscribe(*newSubscr(typ, lower, upper, timeout, unix.TIPC_SUB_PORTS))
+}
+
+// Cancel cancels the subscription and closes C. Events already received
+// by the Mux for it are discarded.
+func (s *Subscription) Cancel() error {
+	if !s.m.remove(s.id) {
+		return nil
+	}
+
+	return s.m.top.Cancel(&s.sub)
+}
+
+// remove stops delivery to the subscription id, reporting whether it was
+// still registered.
+func (m *Mux) remove(id uint64) bool {
+	m.mu.Lock()
+	s, ok := m.subs[id]
+	delete(m.subs, id)
+	m.mu.Unlock()
+
+	if ok {
+		s.close()
+	}
+
+	return ok
+}
+
+func (m *Mux) run(ctx context.Context) {
+	defer close(m.done)
+
+	evc, errc := m.top.Events(ctx)
+
+	for e := range evc {
+		id := handle(&e.Subscription)
+
+		m.mu.Lock()
+		s := m.subs[id]
+		m.mu.Unlock()
+
+		if s == nil {
+			continue
+		}
+
+		s.deliver(e, ctx.Done())
+
+		// The server forgets a subscription once it has timed out.
+		if e.Kind == SubscriptionTimeout {
+			m.remove(id)
+		}
+	}
+
+	err := <-errc
+
+	m.mu.Lock()
+	if ctx.Err() == nil {
+		m.err = err
+	}
+
+	subs := m.subs
+	m.subs = make(map[uint64]*muxSub)
+	m.stopped = true
+	m.mu.Unlock()
+
+	for _, s := range subs {
+		s.close()
+	}
+}
+
+// Err returns the error that stopped the Mux from receiving events, or nil
+// if it is still running or was closed.
+func (m *Mux) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.err
+}
+
+// Close stops the Mux, closes the channels of all its subscriptions and
+// closes the topology connection.
+func (m *Mux) Close() error {
+	m.cancel()
+	<-m.done
+
+	return m.top.Close()
+}
+
+// putHandle stores id in the user handle of sub.
+func putHandle(sub *unix.TIPCSubscr, id uint64) {
+	binary.LittleEndian.PutUint64(handleBytes(sub), id)
+}
+
+// handle returns the id stored in the user handle of sub by putHandle.
+func handle(sub *unix.TIPCSubscr) uint64 {
+	return binary.LittleEndian.Uint64(handleBytes(sub))
+}
+
+// handleBytes returns the user handle of sub as bytes. The element type of
+// Handle follows the signedness of char, which differs between
+// architectures.
+func handleBytes(sub *unix.TIPCSubscr) []byte {
+	return (*[8]byte)(unsafe.Pointer(&sub.Handle))[:]
+}
@@ -0,0 +1,117 @@
+package topology
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mischief/tipc"
+	"golang.org/x/sys/unix"
+)
+
+func TestMuxHandle(t *testing.T) {
+	var sub unix.TIPCSubscr
+
+	for _, id := range []uint64{1, 255, 256, 1<<63 + 7} {
+		putHandle(&sub, id)
+
+		if got := handle(&sub); got != id {
+			t.Errorf("handle = %d, want %d", got, id)
+		}
+	}
+}
+
+func recvEvent(t *testing.T, s *Subscription) Event {
+	t.Helper()
+
+	var (
+		e  Event
+		ok bool
+	)
+
+	select {
+	case e, ok = <-s.C:
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+
+	return e
+}
+
+func TestMux(t *testing.T) {
+	top, err := Topology(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMux(top)
+	defer m.Close()
+
+	s1, err := m.SubscribeService(2004, 0, ^uint32(0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s2, err := m.SubscribeService(2005, 0, ^uint32(0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A second subscription to the same range is routed separately.
+	s3, err := m.SubscribePort(2005, 0, ^uint32(0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := tipc.Listen(unix.TIPC_NODE_SCOPE, &unix.TIPCServiceRange{Type: 2005, Lower: 3, Upper: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	for _, s := range []*Subscription{s2, s3} {
+		e := recvEvent(t, s)
+
+		if e.Kind != Published || e.Subscription.Seq.Type != 2005 || e.Lower != 3 {
+			t.Errorf("unexpected event %s", e)
+		}
+	}
+
+	select {
+	case e := <-s1.C:
+		t.Errorf("event %s routed to the wrong subscription", e)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	l2, err := tipc.Listen(unix.TIPC_NODE_SCOPE, &unix.TIPCServiceRange{Type: 2004, Lower: 9, Upper: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l2.Close()
+
+	if e := recvEvent(t, s1); e.Kind != Published || e.Subscription.Seq.Type != 2004 || e.Lower != 9 {
+		t.Errorf("unexpected event %s", e)
+	}
+
+	if err := s1.Cancel(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := <-s1.C; ok {
+		t.Error("cancelled subscription still open")
+	}
+
+	m.Close()
+
+	if _, ok := <-s2.C; ok {
+		t.Error("subscription open after Close")
+	}
+
+	if _, err := m.SubscribeService(2004, 0, 0, 0); err != ErrMuxClosed {
+		t.Errorf("Subscribe after Close = %v, want %v", err, ErrMuxClosed)
+	}
+}
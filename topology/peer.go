@@ -0,0 +1,65 @@
+package topology
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// scanTimeout is the timeout of the subscription used to list publications.
+// The server reports the existing publications as soon as a subscription is
+// made, ahead of its timeout, so the timeout event marks the end of the
+// list.
+const scanTimeout = time.Millisecond
+
+// PortServices returns the service ranges of type typ overlapping lower to
+// upper that are bound by the socket with the port identity port.
+//
+// TIPC refuses to connect a socket bound to a service, so a client cannot
+// name its connections. A client wanting to be identified by service name can
+// instead publish the name on a separate socket, and send that socket's port
+// identity to the server, which checks the name with PortServices. Only
+// publications visible from the local node are found, so a client on another
+// node must bind with cluster scope.
+func PortServices(port unix.TIPCSocketAddr, typ, lower, upper uint32) ([]unix.TIPCServiceRange, error) {
+	top, err := Topology(0)
+	if err != nil {
+		return nil, err
+	}
+
+	defer top.Close()
+
+	if err := top.SubscribePort(typ, lower, upper, scanTimeout); err != nil {
+		return nil, err
+	}
+
+	top.conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	var ranges []unix.TIPCServiceRange
+
+	for {
+		e, err := top.ReadEvent()
+		if err != nil {
+			return nil, err
+		}
+
+		ev := DecodeEvent(e)
+		r := unix.TIPCServiceRange{Type: typ, Lower: ev.Lower, Upper: ev.Upper}
+
+		switch {
+		case ev.Kind == SubscriptionTimeout:
+			return ranges, nil
+		case e.Port != port:
+			continue
+		case ev.Kind == Published:
+			ranges = append(ranges, r)
+		case ev.Kind == Withdrawn:
+			for i := range ranges {
+				if ranges[i] == r {
+					ranges = append(ranges[:i], ranges[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package topology
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Publication is a service range bound by a socket somewhere in the cluster.
+type Publication struct {
+	Type  uint32
+	Lower uint32
+	Upper uint32
+
+	// PortRef and PortNode identify the publishing socket.
+	PortRef  uint32
+	PortNode uint32
+}
+
+// Resolver maintains the set of current publications of a number of service
+// types, kept up to date from a topology subscription.
+type Resolver struct {
+	top    *TopologyConn
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu   sync.Mutex
+	pubs map[uint32]map[Publication]struct{}
+	err  error
+}
+
+// NewResolver returns a Resolver tracking all publications of the given
+// service types. The set is filled in asynchronously as the topology server
+// reports existing and new publications.
+func NewResolver(types ...uint32) (*Resolver, error) {
+	top, err := Topology(0)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Resolver{
+		top:  top,
+		done: make(chan struct{}),
+		pubs: make(map[uint32]map[Publication]struct{}),
+	}
+
+	for _, typ := range types {
+		if err := top.SubscribePort(typ, 0, ^uint32(0), 0); err != nil {
+			top.Close()
+			return nil, err
+		}
+
+		r.pubs[typ] = make(map[Publication]struct{})
+	}
+
+	var ctx context.Context
+	ctx, r.cancel = context.WithCancel(context.Background())
+
+	go r.run(ctx)
+
+	return r, nil
+}
+
+func (r *Resolver) run(ctx context.Context) {
+	defer close(r.done)
+
+	evc, errc := r.top.Events(ctx)
+
+	for e := range evc {
+		p := Publication{
+			Type:     e.Subscription.Seq.Type,
+			Lower:    e.Lower,
+			Upper:    e.Upper,
+			PortRef:  e.PortRef,
+			PortNode: e.PortNode,
+		}
+
+		r.mu.Lock()
+
+		switch e.Kind {
+		case Published:
+			r.pubs[p.Type][p] = struct{}{}
+		case Withdrawn:
+			delete(r.pubs[p.Type], p)
+		}
+
+		r.mu.Unlock()
+	}
+
+	err := <-errc
+
+	r.mu.Lock()
+	if ctx.Err() == nil {
+		r.err = err
+	}
+	r.mu.Unlock()
+}
+
+// Lookup returns the current publications of service type typ, ordered by
+// instance range, node and port. It returns nil if typ is not tracked by the
+// resolver.
+func (r *Resolver) Lookup(typ uint32) []Publication {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	set := r.pubs[typ]
+	if len(set) == 0 {
+		return nil
+	}
+
+	pubs := make([]Publication, 0, len(set))
+	for p := range set {
+		pubs = append(pubs, p)
+	}
+
+	sort.Slice(pubs, func(i, j int) bool {
+		a, b := pubs[i], pubs[j]
+
+		switch {
+		case a.Lower != b.Lower:
+			return a.Lower < b.Lower
+		case a.Upper != b.Upper:
+			return a.Upper < b.Upper
+		case a.PortNode != b.PortNode:
+			return a.PortNode < b.PortNode
+		}
+
+		return a.PortRef < b.PortRef
+	})
+
+	return pubs
+}
+
+// Err returns the error that stopped the resolver from receiving updates, or
+// nil if it is still running or was closed.
+func (r *Resolver) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.err
+}
+
+// Close stops the resolver and closes its topology connection.
+func (r *Resolver) Close() error {
+	r.cancel()
+	<-r.done
+
+	return r.top.Close()
+}
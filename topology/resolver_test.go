@@ -0,0 +1,72 @@
+package topology
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mischief/tipc"
+	"golang.org/x/sys/unix"
+)
+
+// waitLookup polls r until Lookup(typ) returns n publications.
+func waitLookup(t *testing.T, r *Resolver, typ uint32, n int) []Publication {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		pubs := r.Lookup(typ)
+		if len(pubs) == n {
+			return pubs
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d publications, want %d: %+v", len(pubs), n, pubs)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestResolver(t *testing.T) {
+	const typ = 2003
+
+	r, err := NewResolver(typ)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer r.Close()
+
+	l1, err := tipc.Listen(unix.TIPC_CLUSTER_SCOPE, &unix.TIPCServiceRange{Type: typ, Lower: 1, Upper: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l1.Close()
+
+	l2, err := tipc.Listen(unix.TIPC_CLUSTER_SCOPE, &unix.TIPCServiceRange{Type: typ, Lower: 2, Upper: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pubs := waitLookup(t, r, typ, 2)
+	if pubs[0].Lower != 1 || pubs[1].Lower != 2 {
+		t.Fatalf("unexpected publications %+v", pubs)
+	}
+
+	l2.Close()
+
+	pubs = waitLookup(t, r, typ, 1)
+	if pubs[0].Lower != 1 {
+		t.Fatalf("unexpected publications %+v", pubs)
+	}
+
+	if r.Lookup(typ+1) != nil {
+		t.Error("untracked type returned publications")
+	}
+
+	if err := r.Err(); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,20 +1,92 @@
 package topology
 
 import (
+	"context"
 	"encoding/binary"
+	"time"
 
 	"github.com/mischief/tipc"
 	"golang.org/x/sys/unix"
 )
 
+// Forever is the subscription timeout for subscriptions that never expire.
+// Any timeout of zero or less has the same effect.
+const Forever time.Duration = 0
+
 type TopologyConn struct {
 	conn *tipc.Conn
 }
 
+// Subscribe makes a raw subscription. Its Timeout is in milliseconds, and
+// unix.TIPC_WAIT_FOREVER makes it never expire.
 func (tc *TopologyConn) Subscribe(sub *unix.TIPCSubscr) error {
 	return binary.Write(tc.conn, binary.BigEndian, sub)
 }
 
+// Cancel cancels a subscription previously made on this connection. sub must
+// have the same Seq, Timeout and Filter it was subscribed with.
+func (tc *TopologyConn) Cancel(sub *unix.TIPCSubscr) error {
+	cancel := *sub
+	cancel.Filter |= unix.TIPC_SUB_CANCEL
+
+	return tc.Subscribe(&cancel)
+}
+
+// SubscribeService subscribes to publications of the service type typ
+// overlapping the range lower to upper, reporting one event per overlapping
+// range. The subscription expires with a SubscriptionTimeout event after
+// timeout, which is rounded down to whole milliseconds; a timeout of Forever,
+// or any other of zero or less, means it never expires.
+func (tc *TopologyConn) SubscribeService(typ, lower, upper uint32, timeout time.Duration) error {
+	return tc.Subscribe(newSubscr(typ, lower, upper, timeout, unix.TIPC_SUB_SERVICE))
+}
+
+// SubscribePort is like SubscribeService, but reports one event per
+// publishing socket.
+func (tc *TopologyConn) SubscribePort(typ, lower, upper uint32, timeout time.Duration) error {
+	return tc.Subscribe(newSubscr(typ, lower, upper, timeout, unix.TIPC_SUB_PORTS))
+}
+
+// SubscribeNode subscribes to the reachability of the peer node, or of every
+// peer node if node is 0. When the local node establishes its first link to a
+// peer, it publishes {unix.TIPC_NODE_STATE, <peer address>} with node scope,
+// and withdraws it when the last link to the peer is lost, so a peer becoming
+// reachable is reported as a Published event and its loss as a Withdrawn
+// one; Event.NodeEvent decodes them. The local node never publishes its own
+// address. A zero timeout means the subscription never expires.
+func (tc *TopologyConn) SubscribeNode(node uint32, timeout time.Duration) error {
+	lower, upper := node, node
+	if node == 0 {
+		upper = ^uint32(0)
+	}
+
+	return tc.SubscribeService(unix.TIPC_NODE_STATE, lower, upper, timeout)
+}
+
+func newSubscr(typ, lower, upper uint32, timeout time.Duration, filter uint32) *unix.TIPCSubscr {
+	return &unix.TIPCSubscr{
+		Seq:     unix.TIPCServiceRange{Type: typ, Lower: lower, Upper: upper},
+		Timeout: timeoutMillis(timeout),
+		Filter:  filter,
+	}
+}
+
+// timeoutMillis converts d to the subscription timeout in milliseconds. A
+// duration of zero or less maps to unix.TIPC_WAIT_FOREVER, and finite ones too
+// long to represent are capped just below it, so they never become forever.
+func timeoutMillis(d time.Duration) uint32 {
+	if d <= 0 {
+		return unix.TIPC_WAIT_FOREVER
+	}
+
+	ms := d / time.Millisecond
+	if ms >= unix.TIPC_WAIT_FOREVER {
+		return unix.TIPC_WAIT_FOREVER - 1
+	}
+
+	return uint32(ms)
+}
+
 func (tc *TopologyConn) ReadEvent() (*unix.TIPCEvent, error) {
 	var e unix.TIPCEvent
 
@@ -25,6 +97,67 @@ func (tc *TopologyConn) ReadEvent() (*unix.TIPCEvent, error) {
 	return &e, nil
 }
 
+var aLongTimeAgo = time.Unix(1, 0)
+
+// Events reads events from the topology server in a new goroutine and
+// delivers them on the returned channel until ctx is cancelled or a read
+// fails. The error that stopped the goroutine is then sent on the error
+// channel, and both channels are closed.
+//
+// Events sets the connection's read deadline to unblock a pending read when
+// ctx is cancelled, and clears it again before the channels are closed, so
+// that the connection can be read from again afterwards.
+func (tc *TopologyConn) Events(ctx context.Context) (<-chan Event, <-chan error) {
+	evc := make(chan Event)
+	errc := make(chan error, 1)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-ctx.Done():
+			tc.conn.SetReadDeadline(aLongTimeAgo)
+		case <-done:
+		}
+	}()
+
+	go func() {
+		err := tc.readEvents(ctx, evc)
+
+		close(done)
+		<-stopped
+		tc.conn.SetReadDeadline(time.Time{})
+
+		errc <- err
+		close(evc)
+		close(errc)
+	}()
+
+	return evc, errc
+}
+
+// readEvents delivers events on evc until ctx is cancelled or a read fails.
+func (tc *TopologyConn) readEvents(ctx context.Context, evc chan<- Event) error {
+	for {
+		e, err := tc.ReadEvent()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return err
+		}
+
+		select {
+		case evc <- DecodeEvent(e):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
 func (tc *TopologyConn) Close() error {
 	return tc.conn.Close()
 }
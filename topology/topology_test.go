@@ -1,8 +1,12 @@
 package topology
 
 import (
+	"context"
+	"net"
 	"testing"
+	"time"
 
+	"github.com/mischief/tipc"
 	"golang.org/x/sys/unix"
 )
 
@@ -29,5 +33,285 @@ func TestTopologySubscribe(t *testing.T) {
 		t.Error(err)
 	}
 
-	t.Logf("event: %+v", evt)
+	t.Logf("event: %s", DecodeEvent(evt))
+}
+
+func TestTopologySubscribeService(t *testing.T) {
+	c, err := Topology(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	if err := c.SubscribeService(1, 0, ^uint32(0), time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	evt, err := c.ReadEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if evt.S.Filter != unix.TIPC_SUB_SERVICE || evt.S.Timeout != 1000 {
+		t.Errorf("unexpected subscription echo: %+v", evt.S)
+	}
+}
+
+func TestTimeoutMillis(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want uint32
+	}{
+		{Forever, unix.TIPC_WAIT_FOREVER},
+		{-time.Second, unix.TIPC_WAIT_FOREVER},
+		{time.Millisecond, 1},
+		{1500 * time.Millisecond, 1500},
+	}
+
+	for _, tt := range tests {
+		if got := timeoutMillis(tt.d); got != tt.want {
+			t.Errorf("timeoutMillis(%v) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestSubscribeForever(t *testing.T) {
+	c, err := Topology(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	const timeout = 50 * time.Millisecond
+
+	if err := c.SubscribeService(2009, 0, 0, Forever); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SubscribeService(2009, 1, 1, timeout); err != nil {
+		t.Fatal(err)
+	}
+
+	evt, err := c.ReadEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if e := DecodeEvent(evt); e.Kind != SubscriptionTimeout || e.Subscription.Seq.Lower != 1 {
+		t.Fatalf("got %s, want the timeout of the short subscription", e)
+	}
+
+	// Outlive the short subscription by a wide margin before publishing.
+	time.Sleep(4 * timeout)
+
+	l, err := tipc.ListenReliableDatagram(&unix.SockaddrTIPC{
+		Scope: unix.TIPC_NODE_SCOPE,
+		Addr:  &unix.TIPCServiceRange{Type: 2009, Lower: 0, Upper: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	evt, err = c.ReadEvent()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := DecodeEvent(evt)
+	if e.Kind != Published || e.Subscription.Timeout != unix.TIPC_WAIT_FOREVER {
+		t.Errorf("got %s with timeout %d, want a publication for the forever subscription", e, e.Subscription.Timeout)
+	}
+}
+
+func TestTopologyEvents(t *testing.T) {
+	c, err := Topology(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	if err := c.SubscribeService(1, 0, ^uint32(0), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	evc, errc := c.Events(ctx)
+
+	evt, ok := <-evc
+	if !ok {
+		t.Fatal(<-errc)
+	}
+
+	if evt.Kind != Published {
+		t.Errorf("kind = %d, want %d", evt.Kind, Published)
+	}
+
+	cancel()
+
+	for range evc {
+	}
+
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("error = %v, want %v", err, context.Canceled)
+	}
+
+	// The connection is usable again once Events has stopped.
+	if err := c.SubscribeService(1, 0, ^uint32(0), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	evc, errc = c.Events(ctx)
+
+	select {
+	case evt, ok := <-evc:
+		if !ok {
+			t.Fatalf("second Events: %v", <-errc)
+		}
+
+		if evt.Kind != Published {
+			t.Errorf("second Events: kind = %d, want %d", evt.Kind, Published)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("second Events delivered nothing")
+	}
+}
+
+func TestEventString(t *testing.T) {
+	e := DecodeEvent(&unix.TIPCEvent{
+		Event: unix.TIPC_PUBLISHED,
+		Lower: 1,
+		Upper: 2,
+		Port:  unix.TIPCSocketAddr{Ref: 1234, Node: 0x1001002},
+		S: unix.TIPCSubscr{
+			Seq: unix.TIPCServiceRange{Type: 42, Lower: 0, Upper: 10},
+		},
+	})
+
+	want := "published {42,1,2} port=1234,node=1001002 (subscription {42,0,10})"
+	if got := e.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := EventKind(99).String(); got != "EventKind(99)" {
+		t.Errorf("unknown kind = %q", got)
+	}
+}
+
+func TestTopologyCancel(t *testing.T) {
+	c, err := Topology(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer c.Close()
+
+	sub := &unix.TIPCSubscr{
+		Seq:     unix.TIPCServiceRange{Type: 2000, Lower: 0, Upper: ^uint32(0)},
+		Timeout: unix.TIPC_WAIT_FOREVER,
+		Filter:  unix.TIPC_SUB_SERVICE,
+	}
+
+	if err := c.Subscribe(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Cancel(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if sub.Filter&unix.TIPC_SUB_CANCEL != 0 {
+		t.Error("Cancel modified the caller's subscription")
+	}
+
+	l, err := tipc.Listen(unix.TIPC_CLUSTER_SCOPE, &sub.Seq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer l.Close()
+
+	c.conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+
+	if evt, err := c.ReadEvent(); !isTimeout(err) {
+		t.Errorf("expected no event after cancel, got %+v, %v", evt, err)
+	}
+}
+
+func isTimeout(err error) bool {
+	nerr, ok := err.(net.Error)
+	return ok && nerr.Timeout()
+}
+
+func TestNodeEvent(t *testing.T) {
+	sub := unix.TIPCSubscr{Seq: unix.TIPCServiceRange{Type: unix.TIPC_NODE_STATE, Lower: 0, Upper: ^uint32(0)}}
+
+	for _, tt := range []struct {
+		e    Event
+		want NodeEvent
+		ok   bool
+	}{
+		{Event{Kind: Published, Lower: 0x1001002, Upper: 0x1001002, Subscription: sub}, NodeEvent{0x1001002, true}, true},
+		{Event{Kind: Withdrawn, Lower: 0x1001002, Upper: 0x1001002, Subscription: sub}, NodeEvent{0x1001002, false}, true},
+		{Event{Kind: SubscriptionTimeout, Subscription: sub}, NodeEvent{}, false},
+		{Event{Kind: Published, Subscription: unix.TIPCSubscr{Seq: unix.TIPCServiceRange{Type: 42}}}, NodeEvent{}, false},
+	} {
+		got, ok := tt.e.NodeEvent()
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("NodeEvent(%v) = %v, %v; want %v, %v", tt.e, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSubscribeNode(t *testing.T) {
+	self, err := tipc.NodeAddr()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	top, err := Topology(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer top.Close()
+
+	if err := top.SubscribeNode(0, 200*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	evc, errc := top.Events(ctx)
+
+	// Peer nodes are reported if there are any, but never the local node,
+	// which has no link to itself.
+	for e := range evc {
+		if e.Kind == SubscriptionTimeout {
+			return
+		}
+
+		ne, ok := e.NodeEvent()
+		if !ok {
+			t.Fatalf("unexpected event %v", e)
+		}
+
+		t.Log(ne)
+
+		if ne.Node == self {
+			t.Errorf("event for the local node %x: %v", self, ne)
+		}
+	}
+
+	t.Fatalf("subscription did not time out: %v", <-errc)
 }
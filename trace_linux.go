@@ -0,0 +1,33 @@
+package tipc
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ConnTrace is a set of hooks run at points in the life of a connection made
+// by a Dialer, for instance to record connection latency and traffic. Any
+// hook may be nil. Hooks are called synchronously, from the goroutine that
+// dials or closes the connection.
+type ConnTrace struct {
+	// ConnectStart is called before connecting to addr on network, which is
+	// "tipc-stream" or "tipc-seqpacket".
+	ConnectStart func(network string, addr *Addr)
+
+	// ConnectDone is called when the connection attempt started by
+	// ConnectStart has finished, with the time it took and its error, if
+	// any.
+	ConnectDone func(network string, addr *Addr, d time.Duration, err error)
+
+	// Closed is called when the connection is closed, with the number of
+	// payload bytes read and written over its lifetime.
+	Closed func(read, written int64)
+}
+
+func (tc *Conn) traceClosed() {
+	if tc.trace == nil || tc.trace.Closed == nil {
+		return
+	}
+
+	tc.trace.Closed(atomic.LoadInt64(&tc.rbytes), atomic.LoadInt64(&tc.wbytes))
+}
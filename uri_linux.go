@@ -0,0 +1,142 @@
+package tipc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// ParseURI parses a TIPC URI, returning the network it names and the
+// address. The recognized forms are
+//
+//	tipc://<type>.<instance>[@<node>]
+//	tipc://<type>:<lower>-<upper>[@<node>]
+//	tipc://<address>
+//
+// where <address> is any form accepted by ParseAddr, and <node> restricts a
+// service name lookup to that node, written as for ParseAddr. A service
+// range may not have a node. An optional "?scope=<scope>" suffix sets the
+// scope. The scheme "tipc" selects the "tipc-stream" network; "tipc+stream",
+// "tipc+seqpacket", "tipc+rdm" and "tipc+dgram" select the others.
+func ParseURI(uri string) (network string, addr *Addr, err error) {
+	uerr := func(msg string) error {
+		return &AddrParseError{Addr: uri, Err: msg}
+	}
+
+	i := strings.Index(uri, "://")
+	if i < 0 {
+		return "", nil, uerr("missing scheme")
+	}
+
+	scheme, rest := uri[:i], uri[i+3:]
+
+	switch scheme {
+	case "tipc", "tipc+stream":
+		network = "tipc-stream"
+	case "tipc+seqpacket", "tipc+rdm", "tipc+dgram":
+		network = "tipc-" + strings.TrimPrefix(scheme, "tipc+")
+	default:
+		return "", nil, uerr(fmt.Sprintf("unknown scheme %q", scheme))
+	}
+
+	var query string
+	if j := strings.IndexByte(rest, '?'); j >= 0 {
+		rest, query = rest[:j], rest[j+1:]
+	}
+
+	if query != "" && !strings.HasPrefix(query, "scope=") || strings.Contains(query, "&") {
+		return "", nil, uerr(fmt.Sprintf("unsupported query %q", query))
+	}
+
+	var s string
+
+	if strings.Contains(rest, "=") {
+		s = rest
+	} else {
+		name, node := rest, ""
+		if j := strings.IndexByte(rest, '@'); j >= 0 {
+			name, node = rest[:j], rest[j+1:]
+		}
+
+		if kv := strings.SplitN(name, ":", 2); len(kv) == 2 {
+			if node != "" {
+				return "", nil, uerr("a service range may not have a node")
+			}
+
+			bounds := strings.SplitN(kv[1], "-", 2)
+			if len(bounds) != 2 {
+				return "", nil, uerr("malformed service range")
+			}
+
+			s = fmt.Sprintf("type=%s,lower=%s,upper=%s", kv[0], bounds[0], bounds[1])
+		} else if kv := strings.SplitN(name, ".", 2); len(kv) == 2 {
+			if node == "" {
+				node = "0"
+			}
+
+			s = fmt.Sprintf("type=%s,instance=%s,domain=%s", kv[0], kv[1], node)
+		} else {
+			return "", nil, uerr("unknown address form")
+		}
+	}
+
+	if query != "" {
+		s += "," + query
+	}
+
+	addr, err = ParseAddr(s)
+	if err != nil {
+		if perr, ok := err.(*AddrParseError); ok {
+			return "", nil, uerr(perr.Err)
+		}
+
+		return "", nil, err
+	}
+
+	return network, addr, nil
+}
+
+// DialURI connects to the service or socket named by uri, in any form
+// accepted by ParseURI, on a stream or seqpacket socket. The connection
+// attempt is abandoned when ctx is done.
+func DialURI(ctx context.Context, uri string) (*Conn, error) {
+	network, addr, err := ParseURI(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := addr.Sockaddr.(*unix.SockaddrTIPC).Addr.(*unix.TIPCServiceRange); ok {
+		return nil, &AddrParseError{Addr: uri, Err: "cannot dial a service range"}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		c   *Conn
+		err error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		c, err := DialAddr(network, addr)
+		done <- result{c, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.c, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-done; r.c != nil {
+				r.c.Close()
+			}
+		}()
+
+		return nil, ctx.Err()
+	}
+}